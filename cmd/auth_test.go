@@ -48,7 +48,9 @@ func TestLoginSuccess(t *testing.T) {
 	db := initDb()
 	initData(db)
 
-	ts := httptest.NewServer(App())
+	app, closeApp := App()
+	defer closeApp()
+	ts := httptest.NewServer(app)
 	defer ts.Close()
 	data, _ := json.Marshal(&auth.LoginRequest{
 		Email:    "email4@mail.ru",
@@ -87,7 +89,9 @@ func TestLoginFailed(t *testing.T) {
 	db := initDb()
 	initData(db)
 
-	ts := httptest.NewServer(App())
+	app, closeApp := App()
+	defer closeApp()
+	ts := httptest.NewServer(app)
 	defer ts.Close()
 	data, _ := json.Marshal(&auth.LoginRequest{
 		Email:    "email3@mail.ru",
@@ -1,68 +1,186 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"url/short/configs"
+	"url/short/internal/admin"
+	"url/short/internal/apikey"
 	"url/short/internal/auth"
+	"url/short/internal/domain"
 	"url/short/internal/link"
+	"url/short/internal/session"
 	"url/short/internal/stat"
 	"url/short/internal/user"
+	"url/short/internal/workspace"
 	"url/short/pkg/db"
 	"url/short/pkg/event"
+	"url/short/pkg/geoip"
+	"url/short/pkg/metadata"
 	"url/short/pkg/middleware"
 )
 
-func App() http.Handler {
+// App wires the application. The returned function drains background workers
+// and must be called after the HTTP server stopped accepting requests.
+func App() (http.Handler, func()) {
 	conf := configs.LoadConfig()
 	DB := db.NewDB(conf)
 	router := http.NewServeMux()
 	eventBus := event.NewEventBus()
+	geoDB, err := geoip.Open(conf.Click.GeoIPDb)
+	if err != nil {
+		log.Println("Error loading GeoIP database", err)
+		geoDB = &geoip.DB{}
+	}
 
 	// Repositories
 	linkRepository := link.NewLinkRepository(DB)
 	userRepository := user.NewUserRepository(DB)
 	statRepository := stat.NewStatRepository(DB)
+	sessionRepository := session.NewSessionRepository(DB)
+	apiKeyRepository := apikey.NewAPIKeyRepository(DB)
+	domainRepository := domain.NewDomainRepository(DB)
 
 	// Services
 	authService := auth.NewAuthService(userRepository)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepository, userRepository)
+	tokenService := auth.NewTokenService(&auth.TokenServiceDeps{
+		UserRepository:    userRepository,
+		SessionRepository: sessionRepository,
+		Config:            conf,
+	})
 	statService := stat.NewStatService(&stat.StatServiceDeps{
 		EventBus:       eventBus,
 		StatRepository: statRepository,
+		GeoIP:          geoDB,
+		Config:         conf,
 	})
 
-	linkService := link.NewLinkService(linkRepository, eventBus)
+	var previewService *link.PreviewService
+	if conf.Preview.Enabled {
+		previewService = link.NewPreviewService(&link.PreviewServiceDeps{
+			EventBus:       eventBus,
+			LinkRepository: linkRepository,
+			Fetcher: metadata.NewHTTPFetcher(metadata.HTTPFetcherOptions{
+				Timeout:  conf.Preview.Timeout,
+				MaxBytes: int64(conf.Preview.MaxBytes),
+			}),
+			Config: conf,
+		})
+	}
+
+	aliasCache := link.NewAliasCache(conf)
+	linkService := link.NewLinkService(&link.LinkServiceDeps{
+		LinkRepository:   linkRepository,
+		UserRepository:   userRepository,
+		DomainRepository: domainRepository,
+		EventBus:         eventBus,
+		Cache:            aliasCache,
+		GeoIP:            geoDB,
+	})
+	domainService := domain.NewDomainService(&domain.DomainServiceDeps{
+		DomainRepository: domainRepository,
+		UserRepository:   userRepository,
+		Resolver:         net.DefaultResolver,
+		Hosts:            aliasCache.Hosts,
+	})
+	workspaceService := workspace.NewWorkspaceService(userRepository)
+	adminService := admin.NewAdminService(&admin.AdminServiceDeps{
+		UserRepository:    userRepository,
+		SessionRepository: sessionRepository,
+	})
 
 	// Handler
+	authDeps := middleware.AuthDeps{
+		Config:   conf,
+		Sessions: sessionRepository,
+		APIKeys:  apiKeyService,
+	}
 	auth.NewAuthHandler(router, auth.AuthHandlerDeps{
-		Config:      conf,
-		AuthService: authService,
+		Config:       conf,
+		AuthService:  authService,
+		TokenService: tokenService,
+		Auth:         authDeps,
+	})
+	apikey.NewAPIKeyHandler(router, apikey.APIKeyHandlerDeps{
+		APIKeyService: apiKeyService,
+		Auth:          authDeps,
 	})
 	link.NewLinkHandler(router, link.LinkHandlerDeps{
-		LinkService: linkService,
-		Config:      conf,
+		LinkService:    linkService,
+		StatRepository: statRepository,
+		Config:         conf,
+		Auth:           authDeps,
 	})
 	stat.NewStatHandler(router, stat.StatHandlerDeps{
 		StatRepository: statRepository,
+		StatService:    statService,
+		UserRepository: userRepository,
 		Config:         conf,
+		Auth:           authDeps,
+	})
+	workspace.NewWorkspaceHandler(router, workspace.WorkspaceHandlerDeps{
+		WorkspaceService: workspaceService,
+		Auth:             authDeps,
+	})
+	domain.NewDomainHandler(router, domain.DomainHandlerDeps{
+		DomainService: domainService,
+		Auth:          authDeps,
+	})
+	admin.NewAdminHandler(router, admin.AdminHandlerDeps{
+		AdminService: adminService,
+		LinkService:  linkService,
+		Auth:         authDeps,
 	})
 
-	go statService.AddClick()
+	statService.Start()
+	if previewService != nil {
+		previewService.Start()
+	}
 
 	// Middlewares
 	stack := middleware.Chain(
 		middleware.Cors,
 		middleware.Logging,
 	)
-	return stack(router)
+	return stack(router), func() {
+		statService.Close()
+		if previewService != nil {
+			previewService.Close()
+		}
+	}
 }
 
 func main() {
-	app := App()
+	app, closeApp := App()
 	server := http.Server{
 		Addr:    ":8081",
 		Handler: app,
 	}
-	fmt.Println("Server is listening on port 8081")
-	server.ListenAndServe()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		fmt.Println("Server is listening on port 8081")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	fmt.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Error shutting down server", err)
+	}
+	closeApp()
 }
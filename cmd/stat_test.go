@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"url/short/internal/link"
+	"url/short/internal/stat"
+	"url/short/internal/user"
+)
+
+func TestParallelVisitsCountedExactly(t *testing.T) {
+	const visits = 2000
+
+	// prepare
+	db := initDb()
+	owner := &user.User{
+		Email:    "parallel@mail.ru",
+		Password: "$2a$10$xwLLgG77tJ5x9hWAXJrk0OFq/bpY4i9pojqsmxLyznn45A5.COVb6",
+		Name:     "user",
+	}
+	db.Create(owner)
+	target := link.NewLink("https://go.dev", owner.ID)
+	db.Create(target)
+	defer func() {
+		db.Unscoped().Where("link_id = ?", target.ID).Delete(&stat.Click{})
+		db.Unscoped().Where("link_id = ?", target.ID).Delete(&stat.Stat{})
+		db.Unscoped().Delete(target)
+		db.Unscoped().Delete(owner)
+	}()
+
+	app, closeApp := App()
+	ts := httptest.NewServer(app)
+
+	client := &http.Client{
+		Transport: &http.Transport{MaxConnsPerHost: 100},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < visits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(ts.URL + "/" + target.Hash)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusTemporaryRedirect {
+				t.Errorf("got %d, want %d", res.StatusCode, http.StatusTemporaryRedirect)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ts.Close()
+	closeApp()
+
+	var clicks int64
+	db.Model(&stat.Stat{}).Where("link_id = ?", target.ID).Select("COALESCE(SUM(clicks), 0)").Scan(&clicks)
+	if clicks != visits {
+		t.Fatalf("got %d clicks, want %d", clicks, visits)
+	}
+
+	var rows int64
+	db.Model(&stat.Click{}).Where("link_id = ?", target.ID).Count(&rows)
+	if rows != visits {
+		t.Fatalf("got %d raw clicks, want %d", rows, visits)
+	}
+}
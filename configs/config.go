@@ -2,14 +2,22 @@ package configs
 
 import (
 	"log"
+	"net/http"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Db   Dbconfig
-	Auth Authconfig
+	Db      Dbconfig
+	Auth    Authconfig
+	Link    Linkconfig
+	Click   Clickconfig
+	Preview Previewconfig
 }
 
 type Dbconfig struct {
@@ -17,7 +25,50 @@ type Dbconfig struct {
 }
 
 type Authconfig struct {
-	Secret string
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type Linkconfig struct {
+	// BaseURL is the address short links are printed with, like https://sho.rt.
+	// Without it the address of the request is used.
+	BaseURL string
+	// CacheSize bounds the number of aliases and hosts kept for redirects.
+	CacheSize int
+	CacheTTL  time.Duration
+	// CacheMissTTL is how long unknown aliases are remembered, new links clear them at once.
+	CacheMissTTL time.Duration
+	// RedirectType is the status links without their own one redirect with.
+	RedirectType int
+	// RedirectMaxAge is how long browsers may cache permanent redirects.
+	RedirectMaxAge time.Duration
+}
+
+type Clickconfig struct {
+	// TrustedProxies are allowed to set X-Forwarded-For.
+	TrustedProxies []netip.Prefix
+	// GeoIPDb is a path to a "ip_start,ip_end,country" CSV file.
+	GeoIPDb string
+	// BufferSize bounds the number of clicks waiting to be written, extra clicks are dropped.
+	BufferSize    int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+	// FlushRetries is how many times a failed batch is written again, waiting
+	// RetryBackoff before the first retry and twice as long before each next one.
+	FlushRetries int
+	RetryBackoff time.Duration
+}
+
+type Previewconfig struct {
+	// Enabled turns on fetching titles and images of pages new links point to.
+	Enabled bool
+	Timeout time.Duration
+	// MaxBytes of a page are read, metadata further down is not found.
+	MaxBytes   int
+	BufferSize int
+	Workers    int
 }
 
 func LoadConfig() *Config {
@@ -31,7 +82,98 @@ func LoadConfig() *Config {
 			Dsn: os.Getenv("DSN"),
 		},
 		Auth: Authconfig{
-			Secret: os.Getenv("SECRET"),
+			Secret:     os.Getenv("SECRET"),
+			AccessTTL:  envDuration("ACCESS_TTL", 15*time.Minute),
+			RefreshTTL: envDuration("REFRESH_TTL", 30*24*time.Hour),
 		},
+		Link: Linkconfig{
+			BaseURL:        strings.TrimSuffix(os.Getenv("BASE_URL"), "/"),
+			CacheSize:      envInt("LINK_CACHE_SIZE", 100000),
+			CacheTTL:       envDuration("LINK_CACHE_TTL", time.Minute),
+			CacheMissTTL:   envDuration("LINK_CACHE_MISS_TTL", 10*time.Second),
+			RedirectType:   envRedirectType("REDIRECT_TYPE", http.StatusTemporaryRedirect),
+			RedirectMaxAge: envDuration("REDIRECT_MAX_AGE", time.Hour),
+		},
+		Click: Clickconfig{
+			TrustedProxies: parsePrefixes(os.Getenv("TRUSTED_PROXIES")),
+			GeoIPDb:        os.Getenv("GEOIP_DB"),
+			BufferSize:     envInt("CLICK_BUFFER", 10000),
+			Workers:        envInt("CLICK_WORKERS", 4),
+			BatchSize:      envInt("CLICK_BATCH", 500),
+			FlushInterval:  envDuration("CLICK_FLUSH", time.Second),
+			FlushRetries:   envInt("CLICK_RETRIES", 3),
+			RetryBackoff:   envDuration("CLICK_RETRY_BACKOFF", 100*time.Millisecond),
+		},
+		Preview: Previewconfig{
+			Enabled:    os.Getenv("PREVIEW_DISABLED") != "true",
+			Timeout:    envDuration("PREVIEW_TIMEOUT", 5*time.Second),
+			MaxBytes:   envInt("PREVIEW_MAX_BYTES", 512*1024),
+			BufferSize: envInt("PREVIEW_BUFFER", 1000),
+			Workers:    envInt("PREVIEW_WORKERS", 2),
+		},
+	}
+}
+
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Println("Error parsing", name)
+		return fallback
+	}
+	return n
+}
+
+// envRedirectType reads one of the redirect statuses 301, 302, 307 and 308.
+func envRedirectType(name string, fallback int) int {
+	status := envInt(name, fallback)
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return status
+	}
+	log.Println("Error parsing", name)
+	return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Println("Error parsing", name)
+		return fallback
+	}
+	return d
+}
+
+// parsePrefixes parses a comma separated list of CIDRs or single addresses.
+func parsePrefixes(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				log.Println("Error parsing trusted proxy", item)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			log.Println("Error parsing trusted proxy", item)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
 	}
+	return prefixes
 }
@@ -0,0 +1,6 @@
+package admin
+
+const (
+	ErrUserNotFound = "user not found"
+	ErrSelfUpdate   = "admin can't change own role or disable own account"
+)
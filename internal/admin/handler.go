@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"url/short/internal/link"
+	"url/short/internal/user"
+	"url/short/pkg/middleware"
+	"url/short/pkg/req"
+	"url/short/pkg/res"
+)
+
+type AdminHandlerDeps struct {
+	AdminService *AdminService
+	LinkService  *link.LinkService
+	Auth         middleware.AuthDeps
+}
+
+type AdminHandler struct {
+	AdminService *AdminService
+	LinkService  *link.LinkService
+}
+
+func NewAdminHandler(router *http.ServeMux, deps AdminHandlerDeps) {
+	handler := &AdminHandler{
+		AdminService: deps.AdminService,
+		LinkService:  deps.LinkService,
+	}
+	router.Handle("GET /admin/users", middleware.Authed(handler.GetUsers(), user.RoleAdmin, middleware.ScopeAdmin, deps.Auth))
+	router.Handle("PATCH /admin/users/{id}", middleware.Authed(handler.UpdateUser(), user.RoleAdmin, middleware.ScopeAdmin, deps.Auth))
+	router.Handle("POST /admin/links/{id}/takeover", middleware.Authed(handler.TakeOver(), user.RoleAdmin, middleware.ScopeAdmin, deps.Auth))
+	router.Handle("DELETE /admin/links/{id}", middleware.Authed(handler.DeleteLink(), user.RoleAdmin, middleware.ScopeAdmin, deps.Auth))
+}
+
+func (handler *AdminHandler) GetUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset := 10, 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			l, err := strconv.Atoi(limitStr)
+			if err != nil || l < 0 {
+				http.Error(w, "Error with parsing limit", http.StatusBadRequest)
+				return
+			}
+			limit = l
+		}
+		if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+			o, err := strconv.Atoi(offsetStr)
+			if err != nil || o < 0 {
+				http.Error(w, "Error with parsing offset", http.StatusBadRequest)
+				return
+			}
+			offset = o
+		}
+
+		users, count := handler.AdminService.GetUsers(limit, offset)
+		res.Json(w, &GetAllUsersResponse{
+			Users: users,
+			Count: count,
+		}, http.StatusOK)
+	}
+}
+
+func (handler *AdminHandler) UpdateUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[UpdateUserRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		updated, err := handler.AdminService.UpdateUser(email, uint(id), body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, updated, http.StatusOK)
+	}
+}
+
+func (handler *AdminHandler) TakeOver() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		taken, err := handler.LinkService.TakeOver(email, uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, taken, http.StatusOK)
+	}
+}
+
+func (handler *AdminHandler) DeleteLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.LinkService.Delete(email, uint(id)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case ErrUserNotFound, link.ErrLinkNotFound:
+		return http.StatusNotFound
+	case ErrSelfUpdate, link.ErrLinkForbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
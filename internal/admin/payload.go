@@ -0,0 +1,14 @@
+package admin
+
+import "url/short/internal/user"
+
+type GetAllUsersResponse struct {
+	Users []user.User `json:"users"`
+	Count int64       `json:"count"`
+}
+
+// UpdateUserRequest changes only the fields that are set.
+type UpdateUserRequest struct {
+	Role     *string `json:"role" validate:"omitempty,oneof=admin member viewer"`
+	Disabled *bool   `json:"disabled"`
+}
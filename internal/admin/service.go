@@ -0,0 +1,64 @@
+package admin
+
+import (
+	"errors"
+	"url/short/internal/user"
+	"url/short/pkg/di"
+)
+
+type IUserRepository interface {
+	di.IUserRepository
+	Update(user *user.User) (*user.User, error)
+	Count() int64
+	Get(limit, offset int) []user.User
+}
+
+type AdminServiceDeps struct {
+	UserRepository    IUserRepository
+	SessionRepository di.ISessionRepository
+}
+
+type AdminService struct {
+	UserRepository    IUserRepository
+	SessionRepository di.ISessionRepository
+}
+
+func NewAdminService(deps *AdminServiceDeps) *AdminService {
+	return &AdminService{
+		UserRepository:    deps.UserRepository,
+		SessionRepository: deps.SessionRepository,
+	}
+}
+
+// GetUsers returns paginated list of all users and their total count.
+func (service *AdminService) GetUsers(limit, offset int) ([]user.User, int64) {
+	return service.UserRepository.Get(limit, offset), service.UserRepository.Count()
+}
+
+// UpdateUser changes role or disabled flag of the user with given id.
+// The user is logged out of every session so that the change applies at once.
+func (service *AdminService) UpdateUser(adminEmail string, id uint, body *UpdateUserRequest) (*user.User, error) {
+	existed, err := service.UserRepository.GetById(id)
+	if err != nil {
+		return nil, errors.New(ErrUserNotFound)
+	}
+	if existed.Email == adminEmail {
+		return nil, errors.New(ErrSelfUpdate)
+	}
+
+	if body.Role != nil {
+		existed.Role = *body.Role
+	}
+	if body.Disabled != nil {
+		existed.Disabled = *body.Disabled
+	}
+	updated, err := service.UserRepository.Update(existed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.SessionRepository.RevokeByUser(updated.ID); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"testing"
+	"time"
+	"url/short/internal/session"
+	"url/short/internal/user"
+
+	"gorm.io/gorm"
+)
+
+type MockUserRepository struct {
+	users map[uint]*user.User
+}
+
+func (m *MockUserRepository) Create(u *user.User) (*user.User, error) {
+	return u, nil
+}
+
+func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockUserRepository) GetById(id uint) (*user.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+func (m *MockUserRepository) Update(u *user.User) (*user.User, error) {
+	m.users[u.ID] = u
+	return u, nil
+}
+
+func (m *MockUserRepository) Count() int64 {
+	return int64(len(m.users))
+}
+
+func (m *MockUserRepository) Get(limit, offset int) []user.User {
+	return nil
+}
+
+type MockSessionRepository struct {
+	revoked []uint
+}
+
+func (m *MockSessionRepository) Create(s *session.Session) (*session.Session, error) {
+	return s, nil
+}
+
+func (m *MockSessionRepository) FindByRefreshHash(hash string) (*session.Session, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockSessionRepository) FindByPreviousHash(hash string) (*session.Session, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockSessionRepository) Rotate(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	return false, nil
+}
+
+func (m *MockSessionRepository) Revoke(id uint) error {
+	return nil
+}
+
+func (m *MockSessionRepository) RevokeByUser(userID uint) error {
+	m.revoked = append(m.revoked, userID)
+	return nil
+}
+
+func (m *MockSessionRepository) IsActive(id uint) bool {
+	return false
+}
+
+func bootstrap() (*AdminService, *MockSessionRepository) {
+	sessions := &MockSessionRepository{}
+	service := NewAdminService(&AdminServiceDeps{
+		UserRepository: &MockUserRepository{users: map[uint]*user.User{
+			1: {Model: gorm.Model{ID: 1}, Email: "admin@mail.ru", Role: user.RoleAdmin},
+			2: {Model: gorm.Model{ID: 2}, Email: "a@mail.ru", Role: user.RoleMember},
+		}},
+		SessionRepository: sessions,
+	})
+	return service, sessions
+}
+
+func TestDisableUserRevokesSessions(t *testing.T) {
+	service, sessions := bootstrap()
+	disabled := true
+
+	updated, err := service.UpdateUser("admin@mail.ru", 2, &UpdateUserRequest{Disabled: &disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Disabled || updated.Role != user.RoleMember {
+		t.Fatalf("Expected disabled member, got %+v", updated)
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != 2 {
+		t.Fatalf("Expected sessions of user 2 revoked, got %v", sessions.revoked)
+	}
+}
+
+func TestAdminCantDemoteSelf(t *testing.T) {
+	service, _ := bootstrap()
+	role := user.RoleViewer
+
+	_, err := service.UpdateUser("admin@mail.ru", 1, &UpdateUserRequest{Role: &role})
+	if err == nil || err.Error() != ErrSelfUpdate {
+		t.Fatalf("Expected error %q, got %v", ErrSelfUpdate, err)
+	}
+}
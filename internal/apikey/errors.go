@@ -0,0 +1,7 @@
+package apikey
+
+const (
+	ErrKeyNotFound  = "api key not found"
+	ErrUnknownScope = "unknown scope"
+	ErrUserNotFound = "user not found"
+)
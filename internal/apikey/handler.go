@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"net/http"
+	"strconv"
+	"url/short/pkg/middleware"
+	"url/short/pkg/req"
+	"url/short/pkg/res"
+)
+
+type APIKeyHandlerDeps struct {
+	APIKeyService *APIKeyService
+	Auth          middleware.AuthDeps
+}
+
+type APIKeyHandler struct {
+	APIKeyService *APIKeyService
+}
+
+func NewAPIKeyHandler(router *http.ServeMux, deps APIKeyHandlerDeps) {
+	handler := &APIKeyHandler{
+		APIKeyService: deps.APIKeyService,
+	}
+	router.Handle("POST /auth/keys", keysAuthed(handler.Create(), deps.Auth))
+	router.Handle("GET /auth/keys", keysAuthed(handler.GetAll(), deps.Auth))
+	router.Handle("DELETE /auth/keys/{id}", keysAuthed(handler.Delete(), deps.Auth))
+}
+
+// keysAuthed lets only users logged in with a password manage keys.
+func keysAuthed(next http.Handler, deps middleware.AuthDeps) http.Handler {
+	return middleware.IsAuthed(middleware.RequireScope(next, middleware.ScopeKeysWrite), deps)
+}
+
+func (handler *APIKeyHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[CreateKeyRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		plain, key, err := handler.APIKeyService.Create(email, body.Name, body.Scopes)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, &CreateKeyResponse{
+			Key:    plain,
+			APIKey: key,
+		}, http.StatusCreated)
+	}
+}
+
+func (handler *APIKeyHandler) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		keys, err := handler.APIKeyService.List(email)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, keys, http.StatusOK)
+	}
+}
+
+func (handler *APIKeyHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.APIKeyService.Revoke(email, uint(id)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case ErrKeyNotFound:
+		return http.StatusNotFound
+	case ErrUserNotFound:
+		return http.StatusUnauthorized
+	case ErrUnknownScope:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
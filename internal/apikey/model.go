@@ -0,0 +1,21 @@
+package apikey
+
+import (
+	"time"
+	"url/short/internal/user"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// APIKey is a personal key for programmatic access. Only a sha256 hash of the key is stored.
+type APIKey struct {
+	gorm.Model
+	UserID     uint                        `json:"user_id" gorm:"index"`
+	User       *user.User                  `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name       string                      `json:"name"`
+	Prefix     string                      `json:"prefix"`
+	KeyHash    string                      `json:"-" gorm:"uniqueIndex"`
+	Scopes     datatypes.JSONSlice[string] `json:"scopes"`
+	LastUsedAt *time.Time                  `json:"last_used_at"`
+}
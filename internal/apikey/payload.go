@@ -0,0 +1,12 @@
+package apikey
+
+type CreateKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=64"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateKeyResponse is the only response that contains the key itself.
+type CreateKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
+}
@@ -0,0 +1,60 @@
+package apikey
+
+import (
+	"time"
+	"url/short/pkg/db"
+)
+
+type APIKeyRepository struct {
+	database *db.DB
+}
+
+func NewAPIKeyRepository(database *db.DB) *APIKeyRepository {
+	return &APIKeyRepository{database: database}
+}
+
+func (repo *APIKeyRepository) Create(key *APIKey) (*APIKey, error) {
+	result := repo.database.DB.Create(key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return key, nil
+}
+
+// FindByHash returns the key with its owner loaded.
+func (repo *APIKeyRepository) FindByHash(hash string) (*APIKey, error) {
+	var key APIKey
+	result := repo.database.DB.Preload("User").First(&key, "key_hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &key, nil
+}
+
+func (repo *APIKeyRepository) GetByUser(userID uint) []APIKey {
+	var keys []APIKey
+	repo.database.DB.
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&keys)
+	return keys
+}
+
+// Delete revokes the key of the user, it returns false when there is no such key.
+func (repo *APIKeyRepository) Delete(userID, id uint) (bool, error) {
+	result := repo.database.DB.Where("user_id = ?", userID).Delete(&APIKey{}, id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Touch records usage of the key at most once a minute to keep hot keys cheap.
+func (repo *APIKeyRepository) Touch(id uint, now time.Time) error {
+	return repo.database.DB.Model(&APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-time.Minute)).
+		UpdateColumn("last_used_at", now).Error
+}
@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+	"url/short/pkg/di"
+	"url/short/pkg/middleware"
+)
+
+type APIKeyService struct {
+	APIKeyRepository *APIKeyRepository
+	UserRepository   di.IUserRepository
+}
+
+func NewAPIKeyService(apiKeyRepository *APIKeyRepository, userRepository di.IUserRepository) *APIKeyService {
+	return &APIKeyService{
+		APIKeyRepository: apiKeyRepository,
+		UserRepository:   userRepository,
+	}
+}
+
+// Create generates a key for the user. Without scopes the key gets every API key scope.
+func (service *APIKeyService) Create(email, name string, scopes []string) (string, *APIKey, error) {
+	userID, err := service.userID(email)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(scopes) == 0 {
+		scopes = middleware.APIKeyScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(middleware.APIKeyScopes, scope) {
+			return "", nil, errors.New(ErrUnknownScope)
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	plain := middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key, err := service.APIKeyRepository.Create(&APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  plain[:len(middleware.APIKeyPrefix)+6],
+		KeyHash: hashKey(plain),
+		Scopes:  slices.Clone(scopes),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+func (service *APIKeyService) List(email string) ([]APIKey, error) {
+	userID, err := service.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	return service.APIKeyRepository.GetByUser(userID), nil
+}
+
+func (service *APIKeyService) Revoke(email string, id uint) error {
+	userID, err := service.userID(email)
+	if err != nil {
+		return err
+	}
+	deleted, err := service.APIKeyRepository.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New(ErrKeyNotFound)
+	}
+	return nil
+}
+
+// Authenticate implements di.IAPIKeyAuthenticator.
+func (service *APIKeyService) Authenticate(plain string) (string, string, []string, error) {
+	key, err := service.APIKeyRepository.FindByHash(hashKey(plain))
+	if err != nil || key.User == nil || key.User.Disabled {
+		return "", "", nil, errors.New(ErrKeyNotFound)
+	}
+	service.APIKeyRepository.Touch(key.ID, time.Now())
+	return key.User.Email, key.User.Role, key.Scopes, nil
+}
+
+func (service *APIKeyService) userID(email string) (uint, error) {
+	existedUser, _ := service.UserRepository.FindByEmail(email)
+	if existedUser == nil {
+		return 0, errors.New(ErrUserNotFound)
+	}
+	return existedUser.ID, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
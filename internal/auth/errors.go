@@ -1,6 +1,8 @@
 package auth
 
 const (
-	ErrUserExists = "user exists"
-	ErrWrongCredetials = "wrong email or password"
-)
\ No newline at end of file
+	ErrUserExists          = "user exists"
+	ErrWrongCredetials     = "wrong email or password"
+	ErrInvalidRefreshToken = "invalid refresh token"
+	ErrUserDisabled        = "user is disabled"
+)
@@ -3,7 +3,7 @@ package auth
 import (
 	"net/http"
 	"url/short/configs"
-	"url/short/pkg/jwt"
+	"url/short/pkg/middleware"
 	"url/short/pkg/req"
 	"url/short/pkg/res"
 )
@@ -11,20 +11,26 @@ import (
 type AuthHandlerDeps struct {
 	*configs.Config
 	*AuthService
+	*TokenService
+	Auth middleware.AuthDeps
 }
 
 type AuthHandler struct {
 	*configs.Config
 	*AuthService
+	*TokenService
 }
 
 func NewAuthHandler(router *http.ServeMux, deps AuthHandlerDeps) {
 	handler := &AuthHandler{
-		Config:      deps.Config,
-		AuthService: deps.AuthService,
+		Config:       deps.Config,
+		AuthService:  deps.AuthService,
+		TokenService: deps.TokenService,
 	}
 	router.HandleFunc("POST /auth/login", handler.Login())
 	router.HandleFunc("POST /auth/register", handler.Register())
+	router.HandleFunc("POST /auth/refresh", handler.Refresh())
+	router.Handle("POST /auth/logout", middleware.IsAuthed(handler.Logout(), deps.Auth))
 }
 
 func (handler *AuthHandler) Login() http.HandlerFunc {
@@ -40,16 +46,14 @@ func (handler *AuthHandler) Login() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		token, err := jwt.NewJWT(handler.Config.Auth.Secret).Create(jwt.JWTData{
-			Email: email,
-		})
+		tokens, err := handler.TokenService.Issue(email)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		data := LoginResponse{
-			Token: token,
+			TokenResponse: *tokens,
 		}
 
 		res.Json(w, data, http.StatusOK)
@@ -70,9 +74,7 @@ func (handler *AuthHandler) Register() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		token, err := jwt.NewJWT(handler.Config.Auth.Secret).Create(jwt.JWTData{
-			Email: email,
-		})
+		tokens, err := handler.TokenService.Issue(email)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -80,9 +82,39 @@ func (handler *AuthHandler) Register() http.HandlerFunc {
 		}
 
 		data := RegisterResponse{
-			Token: token,
+			TokenResponse: *tokens,
 		}
 
 		res.Json(w, data, http.StatusCreated)
 	}
 }
+
+func (handler *AuthHandler) Refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[RefreshRequest](&w, r)
+
+		if err != nil {
+			return
+		}
+
+		tokens, err := handler.TokenService.Refresh(body.RefreshToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		res.Json(w, tokens, http.StatusOK)
+	}
+}
+
+func (handler *AuthHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, _ := r.Context().Value(middleware.ContextSessionKey).(uint)
+
+		if err := handler.TokenService.Logout(sessionID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
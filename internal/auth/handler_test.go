@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"url/short/configs"
+	"url/short/internal/session"
 	"url/short/internal/user"
 	"url/short/pkg/db"
 )
@@ -33,16 +35,27 @@ func bootstrap() (*AuthHandler, sqlmock.Sqlmock, error) {
 	userRepository := user.NewUserRepository(&db.DB{
 		DB: gormDB,
 	})
+	sessionRepository := session.NewSessionRepository(&db.DB{
+		DB: gormDB,
+	})
+	config := &configs.Config{
+		Auth: configs.Authconfig{
+			Secret:     "secret",
+			AccessTTL:  time.Minute,
+			RefreshTTL: time.Hour,
+		},
+	}
 
 	handler := AuthHandler{
-		Config: &configs.Config{
-			Auth: configs.Authconfig{
-				Secret: "secret",
-			},
-		},
+		Config: config,
 		AuthService: &AuthService{
 			UserRepository: userRepository,
 		},
+		TokenService: NewTokenService(&TokenServiceDeps{
+			UserRepository:    userRepository,
+			SessionRepository: sessionRepository,
+			Config:            config,
+		}),
 	}
 
 	return &handler, mock, nil
@@ -50,8 +63,12 @@ func bootstrap() (*AuthHandler, sqlmock.Sqlmock, error) {
 
 func TestLoginHandlerSuccess(t *testing.T) {
 	handler, mock, err := bootstrap()
-	rows := sqlmock.NewRows([]string{"email", "password"}).AddRow("email4@mail.ru", "$2a$10$xwLLgG77tJ5x9hWAXJrk0OFq/bpY4i9pojqsmxLyznn45A5.COVb6")
+	rows := sqlmock.NewRows([]string{"id", "email", "password"}).AddRow(1, "email4@mail.ru", "$2a$10$xwLLgG77tJ5x9hWAXJrk0OFq/bpY4i9pojqsmxLyznn45A5.COVb6")
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "email4@mail.ru"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "sessions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
 	if err != nil {
 		t.Fatal(err)
 		return
@@ -84,6 +101,10 @@ func TestRegisterHandlerSuccess(t *testing.T) {
 
 	mock.ExpectQuery(`INSERT INTO "users"`).WillReturnRows(insertRows)
 	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "email4@mail.ru"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "sessions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
 
 	if err != nil {
 		t.Fatal(err)
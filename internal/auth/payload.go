@@ -1,7 +1,15 @@
 package auth
 
+import "time"
+
+type TokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
 type LoginResponse struct {
-	Token string `json:"token"`
+	TokenResponse
 }
 
 type LoginRequest struct {
@@ -16,5 +24,9 @@ type RegisterRequest struct {
 }
 
 type RegisterResponse struct {
-	Token string `json:"token"`
+	TokenResponse
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
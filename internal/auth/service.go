@@ -28,6 +28,10 @@ func (service *AuthService) Login(email, password string) (string, error) {
 		return "", errors.New(ErrWrongCredetials)
 	}
 
+	if existedUser.Disabled {
+		return "", errors.New(ErrUserDisabled)
+	}
+
 	return existedUser.Email, nil
 
 }
@@ -47,6 +51,7 @@ func (service *AuthService) Register(email, password, name string) (string, erro
 		Email:    email,
 		Password: string(hashedPassword),
 		Name:     name,
+		Role:     user.RoleMember,
 	}
 
 	_, err = service.UserRepository.Create(user)
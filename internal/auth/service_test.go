@@ -18,6 +18,10 @@ func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
 	return nil, nil
 }
 
+func (m *MockUserRepository) GetById(id uint) (*user.User, error) {
+	return nil, nil
+}
+
 func TestRegisterSuccess(t *testing.T) {
 	const initialEmail = "a@mail.ru"
 	authService := NewAuthService(&MockUserRepository{})
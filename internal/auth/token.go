@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+	"url/short/configs"
+	"url/short/internal/session"
+	"url/short/internal/user"
+	"url/short/pkg/di"
+	"url/short/pkg/jwt"
+)
+
+type TokenServiceDeps struct {
+	UserRepository    di.IUserRepository
+	SessionRepository di.ISessionRepository
+	Config            *configs.Config
+}
+
+// TokenService issues short-lived access tokens and rotating refresh tokens.
+type TokenService struct {
+	UserRepository    di.IUserRepository
+	SessionRepository di.ISessionRepository
+	jwt               *jwt.JWT
+	accessTTL         time.Duration
+	refreshTTL        time.Duration
+}
+
+func NewTokenService(deps *TokenServiceDeps) *TokenService {
+	return &TokenService{
+		UserRepository:    deps.UserRepository,
+		SessionRepository: deps.SessionRepository,
+		jwt:               jwt.NewJWT(deps.Config.Auth.Secret),
+		accessTTL:         deps.Config.Auth.AccessTTL,
+		refreshTTL:        deps.Config.Auth.RefreshTTL,
+	}
+}
+
+// Issue starts a new session for the user with given email.
+func (service *TokenService) Issue(email string) (*TokenResponse, error) {
+	existedUser, _ := service.UserRepository.FindByEmail(email)
+	if existedUser == nil {
+		return nil, errors.New(ErrWrongCredetials)
+	}
+	if existedUser.Disabled {
+		return nil, errors.New(ErrUserDisabled)
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	created, err := service.SessionRepository.Create(&session.Session{
+		UserID:      existedUser.ID,
+		RefreshHash: hashToken(refreshToken),
+		ExpiresAt:   time.Now().Add(service.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return service.tokens(existedUser, created.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens.
+// A refresh token that was already exchanged revokes its whole session.
+func (service *TokenService) Refresh(refreshToken string) (*TokenResponse, error) {
+	hash := hashToken(refreshToken)
+	current, err := service.SessionRepository.FindByRefreshHash(hash)
+	if err != nil {
+		if reused, _ := service.SessionRepository.FindByPreviousHash(hash); reused != nil {
+			service.SessionRepository.Revoke(reused.ID)
+		}
+		return nil, errors.New(ErrInvalidRefreshToken)
+	}
+	if !current.IsActive(time.Now()) {
+		return nil, errors.New(ErrInvalidRefreshToken)
+	}
+	existedUser, err := service.UserRepository.GetById(current.UserID)
+	if err != nil {
+		return nil, errors.New(ErrInvalidRefreshToken)
+	}
+	if existedUser.Disabled {
+		return nil, errors.New(ErrUserDisabled)
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := service.SessionRepository.Rotate(current.ID, hash, hashToken(newToken), time.Now().Add(service.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, errors.New(ErrInvalidRefreshToken)
+	}
+
+	return service.tokens(existedUser, current.ID, newToken)
+}
+
+// Logout revokes the session, its access and refresh tokens stop working at once.
+func (service *TokenService) Logout(sessionID uint) error {
+	return service.SessionRepository.Revoke(sessionID)
+}
+
+func (service *TokenService) tokens(owner *user.User, sessionID uint, refreshToken string) (*TokenResponse, error) {
+	expiresAt := time.Now().Add(service.accessTTL)
+	token, err := service.jwt.Create(jwt.JWTData{
+		Email:     owner.Email,
+		UserID:    owner.ID,
+		Role:      owner.Role,
+		SessionID: sessionID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"url/short/configs"
+	"url/short/internal/session"
+	"url/short/internal/user"
+
+	"gorm.io/gorm"
+)
+
+type MockTokenUserRepository struct {
+	MockUserRepository
+}
+
+func (m *MockTokenUserRepository) FindByEmail(email string) (*user.User, error) {
+	return &user.User{Model: gorm.Model{ID: 1}, Email: email}, nil
+}
+
+func (m *MockTokenUserRepository) GetById(id uint) (*user.User, error) {
+	return &user.User{Model: gorm.Model{ID: id}, Email: "a@mail.ru"}, nil
+}
+
+type MockSessionRepository struct {
+	sessions map[uint]*session.Session
+}
+
+func (m *MockSessionRepository) Create(s *session.Session) (*session.Session, error) {
+	s.ID = uint(len(m.sessions) + 1)
+	m.sessions[s.ID] = s
+	return s, nil
+}
+
+func (m *MockSessionRepository) FindByRefreshHash(hash string) (*session.Session, error) {
+	for _, s := range m.sessions {
+		if s.RefreshHash == hash {
+			return s, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *MockSessionRepository) FindByPreviousHash(hash string) (*session.Session, error) {
+	for _, s := range m.sessions {
+		if s.PreviousHash == hash {
+			return s, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *MockSessionRepository) Rotate(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	s := m.sessions[id]
+	if s == nil || s.RefreshHash != oldHash {
+		return false, nil
+	}
+	s.PreviousHash, s.RefreshHash, s.ExpiresAt = oldHash, newHash, expiresAt
+	return true, nil
+}
+
+func (m *MockSessionRepository) Revoke(id uint) error {
+	now := time.Now()
+	m.sessions[id].RevokedAt = &now
+	return nil
+}
+
+func (m *MockSessionRepository) RevokeByUser(userID uint) error {
+	return nil
+}
+
+func (m *MockSessionRepository) IsActive(id uint) bool {
+	s := m.sessions[id]
+	return s != nil && s.IsActive(time.Now())
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	sessions := &MockSessionRepository{sessions: map[uint]*session.Session{}}
+	tokenService := NewTokenService(&TokenServiceDeps{
+		UserRepository:    &MockTokenUserRepository{},
+		SessionRepository: sessions,
+		Config: &configs.Config{
+			Auth: configs.Authconfig{Secret: "secret", AccessTTL: time.Minute, RefreshTTL: time.Hour},
+		},
+	})
+
+	issued, err := tokenService.Issue("a@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := tokenService.Refresh(issued.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == issued.RefreshToken {
+		t.Fatal("Expected refresh token to rotate")
+	}
+
+	if _, err := tokenService.Refresh(issued.RefreshToken); err == nil {
+		t.Fatal("Expected reused refresh token to be rejected")
+	}
+	if sessions.IsActive(1) {
+		t.Fatal("Expected reuse of a refresh token to revoke the session")
+	}
+	if _, err := tokenService.Refresh(refreshed.RefreshToken); err == nil {
+		t.Fatal("Expected refresh tokens of a revoked session to be rejected")
+	}
+}
@@ -0,0 +1,9 @@
+package domain
+
+const (
+	ErrDomainNotFound     = "domain not found"
+	ErrDomainInUse        = "domain already registered"
+	ErrDomainNotVerified  = "verification TXT record not found"
+	ErrWorkspaceForbidden = "not an admin of the workspace"
+	ErrUserNotFound       = "user not found"
+)
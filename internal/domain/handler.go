@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"net/http"
+	"strconv"
+	"url/short/internal/user"
+	"url/short/pkg/middleware"
+	"url/short/pkg/req"
+	"url/short/pkg/res"
+)
+
+type DomainHandlerDeps struct {
+	DomainService *DomainService
+	Auth          middleware.AuthDeps
+}
+
+type DomainHandler struct {
+	DomainService *DomainService
+}
+
+func NewDomainHandler(router *http.ServeMux, deps DomainHandlerDeps) {
+	handler := &DomainHandler{
+		DomainService: deps.DomainService,
+	}
+	router.Handle("POST /domain", middleware.Authed(handler.Create(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /domain", middleware.Authed(handler.GetAll(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("POST /domain/{id}/verify", middleware.Authed(handler.Verify(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("DELETE /domain/{id}", middleware.Authed(handler.Delete(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+}
+
+func (handler *DomainHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[CreateDomainRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		domain, err := handler.DomainService.Create(email, body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, NewDomainResponse(domain), http.StatusCreated)
+	}
+}
+
+func (handler *DomainHandler) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		domains, err := handler.DomainService.List(email)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		data := make([]*DomainResponse, 0, len(domains))
+		for i := range domains {
+			data = append(data, NewDomainResponse(&domains[i]))
+		}
+		res.Json(w, data, http.StatusOK)
+	}
+}
+
+func (handler *DomainHandler) Verify() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		domain, err := handler.DomainService.Verify(email, uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, NewDomainResponse(domain), http.StatusOK)
+	}
+}
+
+func (handler *DomainHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.DomainService.Delete(email, uint(id)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case ErrDomainNotFound:
+		return http.StatusNotFound
+	case ErrDomainInUse:
+		return http.StatusConflict
+	case ErrDomainNotVerified:
+		return http.StatusUnprocessableEntity
+	case ErrWorkspaceForbidden:
+		return http.StatusForbidden
+	case ErrUserNotFound:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
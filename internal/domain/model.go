@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+	"url/short/internal/user"
+
+	"gorm.io/gorm"
+)
+
+// VerifyPrefix starts the TXT record that proves ownership of a domain.
+const VerifyPrefix = "shortly-verify="
+
+// Domain is a custom hostname links of a workspace are served on.
+type Domain struct {
+	gorm.Model
+	Host        string          `json:"host" gorm:"uniqueIndex"`
+	WorkspaceID uint            `json:"workspace_id" gorm:"index"`
+	Workspace   *user.Workspace `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	VerifyToken string          `json:"-"`
+	VerifiedAt  *time.Time      `json:"verified_at"`
+}
+
+func (domain *Domain) IsVerified() bool {
+	return domain.VerifiedAt != nil
+}
+
+// VerifyRecord is the name of the TXT record checked by Verify.
+func (domain *Domain) VerifyRecord() string {
+	return "_shortly." + domain.Host
+}
+
+func (domain *Domain) VerifyValue() string {
+	return VerifyPrefix + domain.VerifyToken
+}
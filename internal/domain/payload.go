@@ -0,0 +1,21 @@
+package domain
+
+type CreateDomainRequest struct {
+	Host        string `json:"host" validate:"required,fqdn"`
+	WorkspaceID uint   `json:"workspace_id" validate:"required"`
+}
+
+// DomainResponse tells which TXT record to create to verify the domain.
+type DomainResponse struct {
+	*Domain
+	VerifyRecord string `json:"verify_record"`
+	VerifyValue  string `json:"verify_value"`
+}
+
+func NewDomainResponse(domain *Domain) *DomainResponse {
+	return &DomainResponse{
+		Domain:       domain,
+		VerifyRecord: domain.VerifyRecord(),
+		VerifyValue:  domain.VerifyValue(),
+	}
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"time"
+	"url/short/pkg/db"
+)
+
+type DomainRepository struct {
+	database *db.DB
+}
+
+func NewDomainRepository(database *db.DB) *DomainRepository {
+	return &DomainRepository{database: database}
+}
+
+func (repo *DomainRepository) Create(domain *Domain) (*Domain, error) {
+	result := repo.database.DB.Create(domain)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return domain, nil
+}
+
+func (repo *DomainRepository) GetById(id uint) (*Domain, error) {
+	var domain Domain
+	result := repo.database.DB.First(&domain, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &domain, nil
+}
+
+func (repo *DomainRepository) GetByHost(host string) (*Domain, error) {
+	var domain Domain
+	result := repo.database.DB.First(&domain, "host = ?", host)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &domain, nil
+}
+
+// GetByUser returns domains of the workspaces the user is a member of.
+func (repo *DomainRepository) GetByUser(userID uint) []Domain {
+	var domains []Domain
+	repo.database.DB.
+		Where("workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ?)", userID).
+		Order("id ASC").
+		Find(&domains)
+	return domains
+}
+
+// MarkVerified verifies the domain unless its claim was taken over since it was read.
+func (repo *DomainRepository) MarkVerified(domain *Domain, verifiedAt time.Time) (bool, error) {
+	result := repo.database.DB.Model(&Domain{}).
+		Where("id = ? AND verify_token = ?", domain.ID, domain.VerifyToken).
+		Update("verified_at", verifiedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Claim moves the unverified domain to the workspace with a new token,
+// it returns false when the domain got verified meanwhile.
+func (repo *DomainRepository) Claim(domain *Domain) (bool, error) {
+	result := repo.database.DB.Model(&Domain{}).
+		Where("id = ? AND verified_at IS NULL", domain.ID).
+		Updates(map[string]any{
+			"workspace_id": domain.WorkspaceID,
+			"verify_token": domain.VerifyToken,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (repo *DomainRepository) Delete(id uint) error {
+	return repo.database.DB.Unscoped().Delete(&Domain{}, id).Error
+}
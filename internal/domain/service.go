@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+	"url/short/internal/user"
+	"url/short/pkg/cache"
+	"url/short/pkg/di"
+)
+
+const lookupTimeout = 5 * time.Second
+
+// TXTResolver looks up DNS TXT records, net.DefaultResolver implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type DomainServiceDeps struct {
+	DomainRepository *DomainRepository
+	UserRepository   di.IMembershipRepository
+	Resolver         TXTResolver
+	// Hosts is the cache redirects resolve hosts with, verified or deleted domains are dropped from it.
+	Hosts cache.Cache[string, uint]
+}
+
+type DomainService struct {
+	DomainRepository *DomainRepository
+	UserRepository   di.IMembershipRepository
+	Resolver         TXTResolver
+	Hosts            cache.Cache[string, uint]
+}
+
+func NewDomainService(deps *DomainServiceDeps) *DomainService {
+	hosts := deps.Hosts
+	if hosts == nil {
+		hosts = cache.Nop[string, uint]{}
+	}
+	return &DomainService{
+		DomainRepository: deps.DomainRepository,
+		UserRepository:   deps.UserRepository,
+		Resolver:         deps.Resolver,
+		Hosts:            hosts,
+	}
+}
+
+// Create registers an unverified domain for the workspace, only workspace admins may do it.
+// Unverified domains of other workspaces are taken over.
+func (service *DomainService) Create(email string, body *CreateDomainRequest) (*Domain, error) {
+	if err := service.authorize(email, body.WorkspaceID); err != nil {
+		return nil, err
+	}
+	host := NormalizeHost(body.Host)
+	existed, _ := service.DomainRepository.GetByHost(host)
+	if existed != nil && existed.IsVerified() {
+		return nil, errors.New(ErrDomainInUse)
+	}
+	if existed != nil && existed.WorkspaceID == body.WorkspaceID {
+		return existed, nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	domain := &Domain{
+		Host:        host,
+		WorkspaceID: body.WorkspaceID,
+		VerifyToken: hex.EncodeToString(b),
+	}
+	if existed == nil {
+		return service.DomainRepository.Create(domain)
+	}
+
+	// an unverified claim doesn't hold the host, so it can't be squatted:
+	// another workspace takes it over and whoever verifies first keeps it
+	domain.Model = existed.Model
+	claimed, err := service.DomainRepository.Claim(domain)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New(ErrDomainInUse)
+	}
+	return domain, nil
+}
+
+// List returns domains of the user's workspaces.
+func (service *DomainService) List(email string) ([]Domain, error) {
+	existedUser, _ := service.UserRepository.FindByEmail(email)
+	if existedUser == nil {
+		return nil, errors.New(ErrUserNotFound)
+	}
+	return service.DomainRepository.GetByUser(existedUser.ID), nil
+}
+
+// Verify looks for the TXT record of the domain and marks it verified when found.
+func (service *DomainService) Verify(email string, id uint) (*Domain, error) {
+	domain, err := service.getManaged(email, id)
+	if err != nil {
+		return nil, err
+	}
+	if domain.IsVerified() {
+		return domain, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	records, err := service.Resolver.LookupTXT(ctx, domain.VerifyRecord())
+	if err != nil || !slices.Contains(records, domain.VerifyValue()) {
+		return nil, errors.New(ErrDomainNotVerified)
+	}
+
+	now := time.Now()
+	verified, err := service.DomainRepository.MarkVerified(domain, now)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		// another workspace took the claim over meanwhile
+		return nil, errors.New(ErrDomainNotVerified)
+	}
+	service.Hosts.Delete(domain.Host)
+	domain.VerifiedAt = &now
+	return domain, nil
+}
+
+// Delete removes the domain, links created on it stop resolving.
+func (service *DomainService) Delete(email string, id uint) error {
+	domain, err := service.getManaged(email, id)
+	if err != nil {
+		return err
+	}
+	if err := service.DomainRepository.Delete(domain.ID); err != nil {
+		return err
+	}
+	service.Hosts.Delete(domain.Host)
+	return nil
+}
+
+func (service *DomainService) getManaged(email string, id uint) (*Domain, error) {
+	domain, err := service.DomainRepository.GetById(id)
+	if err != nil {
+		return nil, errors.New(ErrDomainNotFound)
+	}
+	if err := service.authorize(email, domain.WorkspaceID); err != nil {
+		return nil, err
+	}
+	return domain, nil
+}
+
+func (service *DomainService) authorize(email string, workspaceID uint) error {
+	existedUser, _ := service.UserRepository.FindByEmail(email)
+	if existedUser == nil {
+		return errors.New(ErrUserNotFound)
+	}
+	membership, err := service.UserRepository.GetMembership(workspaceID, existedUser.ID)
+	if err != nil || membership.Role != user.RoleAdmin {
+		return errors.New(ErrWorkspaceForbidden)
+	}
+	return nil
+}
+
+// NormalizeHost lowercases host and strips the port and trailing dot.
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
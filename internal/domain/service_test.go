@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"url/short/internal/user"
+	"url/short/pkg/cache"
+	"url/short/pkg/db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type MockUserRepository struct{}
+
+func (m *MockUserRepository) Create(u *user.User) (*user.User, error) {
+	return u, nil
+}
+
+func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+	return &user.User{Model: gorm.Model{ID: 1}, Email: email}, nil
+}
+
+func (m *MockUserRepository) GetById(id uint) (*user.User, error) {
+	return &user.User{Model: gorm.Model{ID: id}}, nil
+}
+
+func (m *MockUserRepository) GetMembership(workspaceID, userID uint) (*user.Membership, error) {
+	return &user.Membership{WorkspaceID: workspaceID, UserID: userID, Role: user.RoleAdmin}, nil
+}
+
+type MockResolver struct {
+	records map[string][]string
+}
+
+func (m *MockResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := m.records[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+func bootstrap(resolver TXTResolver) (*DomainService, sqlmock.Sqlmock, error) {
+	database, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: database,
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service := NewDomainService(&DomainServiceDeps{
+		DomainRepository: NewDomainRepository(&db.DB{DB: gormDB}),
+		UserRepository:   &MockUserRepository{},
+		Resolver:         resolver,
+	})
+	return service, mock, nil
+}
+
+func domainRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "host", "workspace_id", "verify_token"}).AddRow(4, "go.team.dev", 7, "abc")
+}
+
+func TestVerifyWithTXTRecord(t *testing.T) {
+	service, mock, err := bootstrap(&MockResolver{records: map[string][]string{
+		"_shortly.go.team.dev": {"v=spf1 -all", "shortly-verify=abc"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := cache.NewLRU[string, uint](10)
+	// redirects remembered the host while it was unverified
+	hosts.Set("go.team.dev", 0, time.Minute)
+	service.Hosts = hosts
+	mock.ExpectQuery("SELECT").WillReturnRows(domainRows())
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "domains" SET "verified_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	domain, err := service.Verify("a@mail.ru", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !domain.IsVerified() {
+		t.Fatal("Expected domain to be verified")
+	}
+	if _, ok := hosts.Get("go.team.dev"); ok {
+		t.Fatal("Expected the host to be forgotten")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyWithoutTXTRecord(t *testing.T) {
+	service, mock, err := bootstrap(&MockResolver{records: map[string][]string{
+		"_shortly.go.team.dev": {"shortly-verify=other"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT").WillReturnRows(domainRows())
+
+	_, err = service.Verify("a@mail.ru", 4)
+	if err == nil || err.Error() != ErrDomainNotVerified {
+		t.Fatalf("Expected error %q, got %v", ErrDomainNotVerified, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	cases := map[string]string{
+		"Go.Team.Dev":      "go.team.dev",
+		"go.team.dev:8081": "go.team.dev",
+		"go.team.dev.":     "go.team.dev",
+	}
+	for host, want := range cases {
+		if got := NormalizeHost(host); got != want {
+			t.Fatalf("NormalizeHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestCreateTakesOverUnverifiedDomain(t *testing.T) {
+	service, mock, err := bootstrap(&MockResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT").WillReturnRows(domainRows())
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "domains" SET .* WHERE \(id = \$\d AND verified_at IS NULL\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	domain, err := service.Create("a@mail.ru", &CreateDomainRequest{Host: "Go.Team.Dev", WorkspaceID: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain.ID != 4 || domain.WorkspaceID != 8 || domain.VerifyToken == "abc" {
+		t.Fatalf("Expected the claim to move to workspace 8 with a new token, got %+v", domain)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateVerifiedDomainInUse(t *testing.T) {
+	service, mock, err := bootstrap(&MockResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "host", "workspace_id", "verify_token", "verified_at"}).
+		AddRow(4, "go.team.dev", 7, "abc", time.Now()))
+
+	_, err = service.Create("a@mail.ru", &CreateDomainRequest{Host: "go.team.dev", WorkspaceID: 8})
+	if err == nil || err.Error() != ErrDomainInUse {
+		t.Fatalf("Expected error %q, got %v", ErrDomainInUse, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
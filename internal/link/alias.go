@@ -0,0 +1,41 @@
+package link
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// reservedAliases holds first path segments taken by routes other than GET /{alias}.
+// Add new route prefixes here, otherwise a user could claim them as an alias.
+var reservedAliases = map[string]struct{}{
+	"link":        {},
+	"auth":        {},
+	"stat":        {},
+	"api":         {},
+	"admin":       {},
+	"workspace":   {},
+	"domain":      {},
+	"static":      {},
+	"health":      {},
+	"favicon.ico": {},
+	"robots.txt":  {},
+}
+
+func IsReservedAlias(alias string) bool {
+	_, ok := reservedAliases[strings.ToLower(alias)]
+	return ok
+}
+
+// ValidateAlias checks charset, length and reserved words of a user supplied alias.
+func ValidateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return errors.New(ErrAliasInvalid)
+	}
+	if IsReservedAlias(alias) {
+		return errors.New(ErrAliasReserved)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package link
+
+import "testing"
+
+func TestValidateAlias(t *testing.T) {
+	cases := map[string]string{
+		"my-promo_2024": "",
+		"ab":            ErrAliasInvalid,
+		"has space":     ErrAliasInvalid,
+		"dots.qr":       ErrAliasInvalid,
+		"Link":          ErrAliasReserved,
+		"auth":          ErrAliasReserved,
+	}
+
+	for alias, want := range cases {
+		err := ValidateAlias(alias)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Fatalf("alias %q: expected %q, got %q", alias, want, got)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package link
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"url/short/pkg/req"
+	"url/short/pkg/utm"
+)
+
+const (
+	bulkMaxRows  = 5000
+	bulkMaxBytes = 10 << 20
+)
+
+var bulkColumns = []string{
+	"url", "alias", "tags", "expires_at", "max_clicks", "title", "description", "redirect_type",
+	"forward_query", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+}
+
+// parseBulk reads rows of POST /link/bulk: a JSON array of LinkCreateRequest or CSV with
+// a header of bulkColumns, sent as the body or as the "file" field of a multipart form.
+// Rows that can't be parsed or are invalid get an error in results and a nil request.
+func parseBulk(r *http.Request) ([]*LinkCreateRequest, []BulkLinkResult, error) {
+	body := io.Reader(r.Body)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("Error with reading file field")
+		}
+		defer file.Close()
+		body = file
+		mediaType = header.Header.Get("Content-Type")
+		if strings.EqualFold(path.Ext(header.Filename), ".json") {
+			mediaType = "application/json"
+		}
+	}
+
+	var rows []*LinkCreateRequest
+	var results []BulkLinkResult
+	var err error
+	if mediaType == "application/json" {
+		rows, results, err = parseBulkJSON(body)
+	} else {
+		rows, results, err = parseBulkCSV(body)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("no rows to create")
+	}
+
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		if err := req.IsValid(row); err != nil {
+			rows[i] = nil
+			results[i].Error = err.Error()
+		}
+	}
+	return rows, results, nil
+}
+
+func parseBulkJSON(body io.Reader) ([]*LinkCreateRequest, []BulkLinkResult, error) {
+	var rows []*LinkCreateRequest
+	if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return nil, nil, err
+	}
+	if len(rows) > bulkMaxRows {
+		return nil, nil, fmt.Errorf("at most %d rows are allowed", bulkMaxRows)
+	}
+
+	results := make([]BulkLinkResult, len(rows))
+	for i, row := range rows {
+		results[i].Row = i + 1
+		if row == nil {
+			results[i].Error = "row must be an object"
+		}
+	}
+	return rows, results, nil
+}
+
+func parseBulkCSV(body io.Reader) ([]*LinkCreateRequest, []BulkLinkResult, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("Error with reading CSV header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(bulkColumns, name) {
+			return nil, nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, nil, errors.New("url column is required")
+	}
+
+	var rows []*LinkCreateRequest
+	var results []BulkLinkResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) == bulkMaxRows {
+			return nil, nil, fmt.Errorf("at most %d rows are allowed", bulkMaxRows)
+		}
+
+		result := BulkLinkResult{Row: len(rows) + 1}
+		row, err := parseBulkRecord(record, columns)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		rows = append(rows, row)
+		results = append(results, result)
+	}
+	return rows, results, nil
+}
+
+func parseBulkRecord(record []string, columns map[string]int) (*LinkCreateRequest, error) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := &LinkCreateRequest{
+		Url:   value("url"),
+		Alias: value("alias"),
+		Tags: strings.FieldsFunc(value("tags"), func(r rune) bool {
+			return r == ',' || r == ';'
+		}),
+		Title:       value("title"),
+		Description: value("description"),
+		UTM: utm.Params{
+			Source:   value("utm_source"),
+			Medium:   value("utm_medium"),
+			Campaign: value("utm_campaign"),
+			Term:     value("utm_term"),
+			Content:  value("utm_content"),
+		},
+	}
+	if expiresAt := value("expires_at"); expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, errors.New("Error with parse expires_at")
+		}
+		row.ExpiresAt = &t
+	}
+	if maxClicks := value("max_clicks"); maxClicks != "" {
+		n, err := strconv.ParseUint(maxClicks, 10, 32)
+		if err != nil {
+			return nil, errors.New("Error with parse max_clicks")
+		}
+		clicks := uint(n)
+		row.MaxClicks = &clicks
+	}
+	if redirectType := value("redirect_type"); redirectType != "" {
+		status, err := strconv.Atoi(redirectType)
+		if err != nil {
+			return nil, errors.New("Error with parse redirect_type")
+		}
+		row.RedirectType = status
+	}
+	if forwardQuery := value("forward_query"); forwardQuery != "" {
+		forward, err := strconv.ParseBool(forwardQuery)
+		if err != nil {
+			return nil, errors.New("Error with parse forward_query")
+		}
+		row.ForwardQuery = forward
+	}
+	return row, nil
+}
@@ -0,0 +1,85 @@
+package link
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBulkCSV(t *testing.T) {
+	body := "URL,alias,tags,max_clicks\n" +
+		"https://go.dev,golang,\"Go, docs\",\n" +
+		"not a url,,,\n" +
+		"https://pkg.go.dev,,,many\n"
+	r := httptest.NewRequest(http.MethodPost, "/link/bulk", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/csv")
+
+	rows, results, err := parseBulk(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || len(results) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	if rows[0] == nil || rows[0].Alias != "golang" || !slices.Equal(NormalizeTags(rows[0].Tags), []string{"go", "docs"}) {
+		t.Fatalf("Unexpected first row %+v", rows[0])
+	}
+	for i := 1; i < 3; i++ {
+		if rows[i] != nil || results[i].Error == "" || results[i].Row != i+1 {
+			t.Fatalf("Expected row %d to fail, got %+v", i+1, results[i])
+		}
+	}
+}
+
+func TestParseBulkUnknownColumn(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/link/bulk", strings.NewReader("url,owner\nhttps://go.dev,me\n"))
+	r.Header.Set("Content-Type", "text/csv")
+
+	if _, _, err := parseBulk(r); err == nil {
+		t.Fatal("Expected unknown column error")
+	}
+}
+
+func TestParseBulkJSONFile(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "links.json")
+	file.Write([]byte(`[{"url": "https://go.dev", "tags": ["go"]}, {"url": "https://pkg.go.dev", "alias": "pkg"}]`))
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, "/link/bulk", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+
+	rows, results, err := parseBulk(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].Alias != "pkg" || results[0].Error != "" {
+		t.Fatalf("Unexpected rows %+v %+v", rows, results)
+	}
+}
+
+func TestCreateBulkAtomicRollsBack(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []*LinkCreateRequest{{Url: "https://go.dev"}, nil}
+	results := []BulkLinkResult{{Row: 1}, {Row: 2, Error: "invalid url"}}
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	response, err := service.CreateBulk("a@mail.ru", rows, results, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Created != 0 || response.Failed != 2 || response.Results[0].Error != ErrBulkFailed {
+		t.Fatalf("Unexpected response %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+package link
+
+import (
+	"sync"
+	"time"
+	"url/short/configs"
+	"url/short/pkg/cache"
+)
+
+// AliasKey is what redirects look a link up by, DomainID 0 is the service's own host.
+type AliasKey struct {
+	DomainID uint
+	Alias    string
+}
+
+// AliasCache keeps links by alias and verified custom domains by host in front of the database,
+// so a redirect usually makes no query. Unknown aliases are kept as nil links for MissTTL.
+// Links and Hosts may be replaced by a shared store when the service runs on several instances.
+type AliasCache struct {
+	Links   cache.Cache[AliasKey, *Link]
+	Hosts   cache.Cache[string, uint]
+	TTL     time.Duration
+	MissTTL time.Duration
+
+	// forgotten counts forget calls, so a lookup that read the database
+	// before one does not store what it read afterwards.
+	mu        sync.RWMutex
+	forgotten uint64
+}
+
+func NewAliasCache(conf *configs.Config) *AliasCache {
+	return &AliasCache{
+		Links:   cache.NewLRU[AliasKey, *Link](conf.Link.CacheSize),
+		Hosts:   cache.NewLRU[string, uint](conf.Link.CacheSize),
+		TTL:     conf.Link.CacheTTL,
+		MissTTL: conf.Link.CacheMissTTL,
+	}
+}
+
+// noCache is used by services created without a cache.
+func noCache() *AliasCache {
+	return &AliasCache{
+		Links: cache.Nop[AliasKey, *Link]{},
+		Hosts: cache.Nop[string, uint]{},
+	}
+}
+
+// link returns a copy of the cached link, nil with true for a known missing alias.
+func (c *AliasCache) link(domainID uint, alias string) (*Link, bool) {
+	link, ok := c.Links.Get(AliasKey{DomainID: domainID, Alias: alias})
+	if !ok || link == nil {
+		return nil, ok
+	}
+	copied := *link
+	return &copied, true
+}
+
+// version is taken before reading a link from the database and passed to setLink.
+func (c *AliasCache) version() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.forgotten
+}
+
+// setLink keeps the link of the alias, nil remembers that there is none.
+// Nothing is kept when any alias was forgotten since version was taken,
+// the link may have been read before the change that forgot it.
+func (c *AliasCache) setLink(version uint64, domainID uint, alias string, link *Link) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.forgotten != version {
+		return
+	}
+
+	ttl := c.TTL
+	if link == nil {
+		ttl = c.MissTTL
+	} else {
+		copied := *link
+		link = &copied
+	}
+	c.Links.Set(AliasKey{DomainID: domainID, Alias: alias}, link, ttl)
+}
+
+// forget drops the alias so the next redirect reads it from the database.
+func (c *AliasCache) forget(domainID uint, alias string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgotten++
+	c.Links.Delete(AliasKey{DomainID: domainID, Alias: alias})
+}
+
+func (c *AliasCache) host(host string) (uint, bool) {
+	return c.Hosts.Get(host)
+}
+
+// setHost keeps the verified domain of the host, 0 for hosts that are not one.
+func (c *AliasCache) setHost(host string, domainID uint) {
+	ttl := c.TTL
+	if domainID == 0 {
+		ttl = c.MissTTL
+	}
+	c.Hosts.Set(host, domainID, ttl)
+}
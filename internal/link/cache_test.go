@@ -0,0 +1,108 @@
+package link
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"url/short/configs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVisitCachesAliases(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.cache = NewAliasCache(&configs.Config{Link: configs.Linkconfig{
+		CacheSize:    10,
+		CacheTTL:     time.Minute,
+		CacheMissTTL: time.Minute,
+	}})
+	columns := []string{"id", "url", "hash", "domain_id", "user_id"}
+	byAlias := `WHERE \(domain_id = \$1 AND hash = \$2\)`
+
+	mock.ExpectQuery(byAlias).WithArgs(4, "promo", 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "https://go.dev", "promo", 4, 1))
+	expectNoRules(mock)
+	mock.ExpectQuery(byAlias).WithArgs(0, "nope", 1).WillReturnRows(sqlmock.NewRows(columns))
+	for i := 0; i < 3; i++ {
+		link, _, err := service.Visit(&VisitRequest{Host: "go.team.dev", Alias: "promo"})
+		if err != nil || link.Url != "https://go.dev" {
+			t.Fatalf("Expected cached link, got %v", err)
+		}
+		// callers may change the link they got without touching the cache
+		link.Url = "https://evil.dev"
+		if _, _, err := service.Visit(&VisitRequest{Host: "sho.rt", Alias: "nope"}); err == nil || err.Error() != ErrLinkNotFound {
+			t.Fatalf("Expected error %q, got %v", ErrLinkNotFound, err)
+		}
+	}
+
+	// deleting the link drops it from the cache
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "https://go.dev", "promo", 4, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "links" SET "deleted_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := service.Delete("admin@mail.ru", 5); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(byAlias).WithArgs(4, "promo", 1).WillReturnRows(sqlmock.NewRows(columns))
+	if _, _, err := service.Visit(&VisitRequest{Host: "go.team.dev", Alias: "promo"}); err == nil || err.Error() != ErrLinkNotFound {
+		t.Fatalf("Expected error %q, got %v", ErrLinkNotFound, err)
+	}
+
+	// failed queries are not remembered as missing aliases
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(byAlias).WithArgs(0, "down", 1).WillReturnError(errors.New("connection refused"))
+		if _, _, err := service.Visit(&VisitRequest{Alias: "down"}); err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailedUpdateForgetsAlias(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.cache = NewAliasCache(&configs.Config{Link: configs.Linkconfig{CacheSize: 10, CacheTTL: time.Minute}})
+	service.cache.setLink(0, 0, "promo", &Link{Url: "https://go.dev", Hash: "promo"})
+	service.cache.setLink(0, 0, "fresh", nil)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "promo", 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "links" WHERE \(domain_id = \$1 AND hash = \$2\)`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`UPDATE "links"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	if _, err := service.Update("a@mail.ru", 5, &LinkUpdateRequest{Url: "https://go.dev/doc", Hash: "fresh"}); err == nil {
+		t.Fatal("Expected error")
+	}
+	for _, alias := range []string{"promo", "fresh"} {
+		if _, ok := service.cache.link(0, alias); ok {
+			t.Fatalf("Expected %s to be forgotten", alias)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForgetWinsOverStaleLookup(t *testing.T) {
+	cache := NewAliasCache(&configs.Config{Link: configs.Linkconfig{CacheSize: 10, CacheTTL: time.Minute}})
+
+	// a redirect read the link, then an update committed and forgot it
+	version := cache.version()
+	cache.forget(0, "promo")
+	cache.setLink(version, 0, "promo", &Link{Url: "https://go.dev", Hash: "promo"})
+	if _, ok := cache.link(0, "promo"); ok {
+		t.Fatal("Expected stale link not to be cached")
+	}
+
+	cache.setLink(cache.version(), 0, "promo", &Link{Url: "https://go.dev/doc", Hash: "promo"})
+	if link, ok := cache.link(0, "promo"); !ok || link.Url != "https://go.dev/doc" {
+		t.Fatalf("Expected fresh link to be cached, got %+v", link)
+	}
+}
@@ -0,0 +1,19 @@
+package link
+
+const (
+	ErrLinkNotFound       = "link not found"
+	ErrLinkExpired        = "link has expired"
+	ErrLinkForbidden      = "link belongs to another user"
+	ErrLinkLocked         = "link is password protected"
+	ErrWrongPassword      = "wrong password"
+	ErrUserNotFound       = "user not found"
+	ErrAliasInUse         = "alias already in use"
+	ErrAliasInvalid       = "alias must be 3-32 characters of letters, digits, '-' or '_'"
+	ErrAliasReserved      = "alias is reserved"
+	ErrWorkspaceForbidden = "not a member of the workspace"
+	ErrDomainInvalid      = "domain is not registered or not verified"
+	ErrFolderNotFound     = "folder not found"
+	ErrFolderInvalid      = "folder belongs to another user or workspace"
+	ErrBulkFailed         = "some rows failed, no link was created"
+	ErrRuleInvalid        = "rule id must be a distinct rule of the link"
+)
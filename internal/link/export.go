@@ -0,0 +1,78 @@
+package link
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"url/short/pkg/utm"
+)
+
+var linkExportHeader = []string{
+	"id", "hash", "url", "user_id", "workspace_id", "domain_id",
+	"created_at", "expires_at", "max_clicks", "clicks_used", "tags",
+	"title", "description", "folder_id", "redirect_type", "forward_query",
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+}
+
+// LinkExport is a row of GET /link/export.
+type LinkExport struct {
+	ID          uint       `json:"id"`
+	Hash        string     `json:"hash"`
+	Url         string     `json:"url"`
+	UserID      uint       `json:"user_id"`
+	WorkspaceID *uint      `json:"workspace_id"`
+	DomainID    uint       `json:"domain_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxClicks   *uint      `json:"max_clicks"`
+	ClicksUsed  uint       `json:"clicks_used"`
+	TagNames    string     `json:"-"`
+	Tags        []string   `json:"tags" gorm:"-"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	FolderID    *uint      `json:"folder_id"`
+	// RedirectType is 0 for links redirecting with the server default.
+	RedirectType int        `json:"redirect_type"`
+	ForwardQuery bool       `json:"forward_query"`
+	UTM          utm.Params `json:"utm" gorm:"embedded;embeddedPrefix:utm_"`
+}
+
+func (row *LinkExport) CSV() []string {
+	return []string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		row.Hash,
+		row.Url,
+		strconv.FormatUint(uint64(row.UserID), 10),
+		formatUint(row.WorkspaceID),
+		strconv.FormatUint(uint64(row.DomainID), 10),
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		formatTime(row.ExpiresAt),
+		formatUint(row.MaxClicks),
+		strconv.FormatUint(uint64(row.ClicksUsed), 10),
+		strings.Join(row.Tags, ","),
+		row.Title,
+		row.Description,
+		formatUint(row.FolderID),
+		strconv.Itoa(row.RedirectType),
+		strconv.FormatBool(row.ForwardQuery),
+		row.UTM.Source,
+		row.UTM.Medium,
+		row.UTM.Campaign,
+		row.UTM.Term,
+		row.UTM.Content,
+	}
+}
+
+func formatUint(n *uint) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*n), 10)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
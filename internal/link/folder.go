@@ -0,0 +1,27 @@
+package link
+
+import "gorm.io/gorm"
+
+// Folder groups links of a user or, when WorkspaceID is set, of a workspace.
+// A link is in at most one folder.
+type Folder struct {
+	gorm.Model
+	Name        string `json:"name"`
+	UserID      uint   `json:"user_id" gorm:"index"`
+	WorkspaceID *uint  `json:"workspace_id" gorm:"index"`
+}
+
+// Holds reports whether links of the owner and workspace may be put into the folder:
+// personal folders hold personal links of their owner, workspace folders links of the workspace.
+func (folder *Folder) Holds(userID uint, workspaceID *uint) bool {
+	if folder.WorkspaceID == nil {
+		return workspaceID == nil && folder.UserID == userID
+	}
+	return workspaceID != nil && *folder.WorkspaceID == *workspaceID
+}
+
+// TagCount is a tag with the number of links it labels.
+type TagCount struct {
+	Name  string `json:"name"`
+	Links int64  `json:"links"`
+}
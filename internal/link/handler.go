@@ -1,33 +1,59 @@
 package link
 
 import (
-    "net/http"
-    "strconv"
-    "url/short/configs"
-    "url/short/pkg/middleware"
-    "url/short/pkg/req"
-    "url/short/pkg/res"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url/short/configs"
+	"url/short/internal/stat"
+	"url/short/internal/user"
+	"url/short/pkg/cursor"
+	"url/short/pkg/export"
+	"url/short/pkg/middleware"
+	"url/short/pkg/req"
+	"url/short/pkg/res"
 )
 
 type LinkHandlerDeps struct {
-    LinkService *LinkService
-    Config      *configs.Config
+	LinkService    *LinkService
+	StatRepository *stat.StatRepository
+	Config         *configs.Config
+	Auth           middleware.AuthDeps
 }
 
 type LinkHandler struct {
-    LinkService *LinkService
+	LinkService    *LinkService
+	StatRepository *stat.StatRepository
+	Config         *configs.Config
 }
 
 func NewLinkHandler(router *http.ServeMux, deps LinkHandlerDeps) {
 
-    handler := &LinkHandler{
-        LinkService: deps.LinkService,
-    }
-    router.HandleFunc("POST /link", handler.Create())
-    router.HandleFunc("GET /link", handler.GetAll())
-    router.Handle("PATCH /link/{id}", middleware.IsAuthed(handler.Update(), deps.Config))
-    router.HandleFunc("DELETE /link/{id}", handler.Delete())
-    router.HandleFunc("GET /{alias}", handler.GoTo())
+	handler := &LinkHandler{
+		LinkService:    deps.LinkService,
+		StatRepository: deps.StatRepository,
+		Config:         deps.Config,
+	}
+	router.Handle("POST /link", middleware.Authed(handler.Create(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("POST /link/bulk", middleware.Authed(handler.CreateBulk(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /link/export", middleware.Authed(handler.Export(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("GET /link", middleware.Authed(handler.GetAll(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("GET /link/tags", middleware.Authed(handler.GetTags(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("POST /link/{id}/tags", middleware.Authed(handler.AddTags(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("DELETE /link/{id}/tags/{tag}", middleware.Authed(handler.RemoveTag(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("POST /link/folders", middleware.Authed(handler.CreateFolder(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /link/folders", middleware.Authed(handler.GetFolders(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("DELETE /link/folders/{id}", middleware.Authed(handler.DeleteFolder(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /link/{id}/rules", middleware.Authed(handler.GetRules(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("PUT /link/{id}/rules", middleware.Authed(handler.SetRules(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("PATCH /link/{id}", middleware.Authed(handler.Update(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("DELETE /link/{id}", middleware.Authed(handler.Delete(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /link/{id}/qr", middleware.Authed(handler.QR(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("GET /link/{id}/stats", middleware.Authed(handler.Stats(), user.RoleViewer, middleware.ScopeStatsRead, deps.Auth))
+	router.HandleFunc("GET /{alias}", handler.GoTo())
+	router.HandleFunc("POST /{alias}", handler.Unlock())
 
 }
 
@@ -39,115 +65,564 @@ func (handler *LinkHandler) Create() http.HandlerFunc {
 			return
 		}
 
-    createdLink, err := handler.LinkService.Create(body.Url)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		createdLink, err := handler.LinkService.Create(email, body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
 
-    res.Json(w, createdLink, http.StatusCreated)
+		res.Json(w, createdLink, http.StatusCreated)
 
 	}
 
 }
 
+// CreateBulk creates links from a JSON array or CSV, see parseBulk.
+// With atomic=true either every link is created or none.
+func (handler *LinkHandler) CreateBulk() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, bulkMaxBytes)
+		rows, results, err := parseBulk(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		atomic := r.URL.Query().Get("atomic") == "true"
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		response, err := handler.LinkService.CreateBulk(email, rows, results, atomic)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		status := http.StatusCreated
+		if response.Failed > 0 && atomic {
+			status = http.StatusUnprocessableEntity
+		} else if response.Failed > 0 {
+			status = http.StatusOK
+		}
+		res.Json(w, response, status)
+	}
+}
+
 func (handler *LinkHandler) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-    body, err := req.HandleBody[LinkUpdateRequest](&w, r)
-    if err != nil {
-        return
-    }
+		body, err := req.HandleBody[LinkUpdateRequest](&w, r)
+		if err != nil {
+			return
+		}
 
-    idString := r.PathValue("id")
-    id, err := strconv.ParseInt(idString, 10, 32)
+		idString := r.PathValue("id")
+		id, err := strconv.ParseInt(idString, 10, 32)
 
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-    link, err := handler.LinkService.Update(uint(id), body.Url, body.Hash)
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		link, err := handler.LinkService.Update(email, uint(id), body)
 
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
 
-    res.Json(w, link, http.StatusOK)
+		res.Json(w, link, http.StatusOK)
 	}
 }
 
 func (handler *LinkHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idString := r.PathValue("id")
-    id, err := strconv.ParseInt(idString, 10, 32)
+		id, err := strconv.ParseInt(idString, 10, 32)
 
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-    // ensure exists
-    _, err = handler.LinkService.GetByID(uint(id))
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusNotFound)
-        return
-    }
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		err = handler.LinkService.Delete(email, uint(id))
 
-    err = handler.LinkService.Delete(uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	}
+}
+
+func (handler *LinkHandler) AddTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[LinkTagsRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
-    }
-    w.WriteHeader(http.StatusNoContent)
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		link, err := handler.LinkService.AddTags(email, uint(id), body.Tags)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
 
+		res.Json(w, link, http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) GetRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		rules, err := handler.LinkService.GetRules(email, uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, rules, http.StatusOK)
+	}
+}
+
+// SetRules replaces redirect rules of the link with the ordered list of the body.
+func (handler *LinkHandler) SetRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[LinkRulesRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		rules, err := handler.LinkService.SetRules(email, uint(id), body.Rules)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, rules, http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) RemoveTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		err = handler.LinkService.RemoveTag(email, uint(id), r.PathValue("tag"))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetTags lists tags of the visible links, or of workspace_id, with the number of links of each.
+func (handler *LinkHandler) GetTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseLinkQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		tags, err := handler.LinkService.GetTags(email, query.WorkspaceID)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, tags, http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) CreateFolder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[FolderCreateRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		folder, err := handler.LinkService.CreateFolder(email, body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, folder, http.StatusCreated)
+	}
+}
+
+func (handler *LinkHandler) GetFolders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseLinkQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		folders, err := handler.LinkService.GetFolders(email, query.WorkspaceID)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, folders, http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) DeleteFolder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.LinkService.DeleteFolder(email, uint(id)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// QR returns the QR code of a link as PNG or SVG, see parseQR for the options.
+func (handler *LinkHandler) QR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		link, err := handler.LinkService.GetReadable(email, uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		handler.writeQR(w, r, link)
+	}
+}
+
+// aliasQR serves GET /{alias}.qr, anyone who knows the alias may print its code.
+func (handler *LinkHandler) aliasQR(w http.ResponseWriter, r *http.Request, alias string) {
+	link, err := handler.LinkService.Find(r.Host, alias)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	handler.writeQR(w, r, link)
+}
+
+func (handler *LinkHandler) Stats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idString := r.PathValue("id")
+		id, err := strconv.ParseInt(idString, 10, 32)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query, err := parseStatsQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if _, err := handler.LinkService.GetReadable(email, uint(id)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, handler.StatRepository.GetLinkStats(uint(id), query), http.StatusOK)
 	}
 }
 
 func (handler *LinkHandler) GoTo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-    hash := r.PathValue("alias")
-    link, err := handler.LinkService.Visit(hash)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusNotFound)
-        return
-    }
-    http.Redirect(w, r, link.Url, http.StatusTemporaryRedirect)
+		hash := r.PathValue("alias")
+		if alias, ok := strings.CutSuffix(hash, qrSuffix); ok {
+			handler.aliasQR(w, r, alias)
+			return
+		}
+		source := ""
+		if r.URL.Query().Get("src") == SourceQR {
+			source = SourceQR
+		}
+		link, target, err := handler.LinkService.Visit(&VisitRequest{
+			Host:      r.Host,
+			Alias:     hash,
+			IP:        req.ClientIP(r, handler.Config.Click.TrustedProxies),
+			UserAgent: r.UserAgent(),
+			Referrer:  r.Referer(),
+			Source:    source,
+			Query:     r.URL.Query(),
+			Language:  r.Header.Get("Accept-Language"),
+			Unlocked: func(link *Link) bool {
+				return handler.isUnlocked(r, link)
+			},
+		})
+		if err != nil && err.Error() == ErrLinkLocked {
+			renderUnlockPage(w, hash, "", http.StatusOK)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		handler.redirect(w, r, link, target)
 	}
 }
 
+func (handler *LinkHandler) Unlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hash := r.PathValue("alias")
+		link, err := handler.LinkService.Unlock(r.Host, hash, r.PostFormValue("password"))
+		if err != nil && err.Error() == ErrWrongPassword {
+			renderUnlockPage(w, hash, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		expires := time.Now().Add(unlockCookieTTL)
+		http.SetCookie(w, &http.Cookie{
+			Name:     unlockCookieName(hash),
+			Value:    signUnlock(handler.Config.Auth.Secret, link, expires),
+			Path:     "/" + hash,
+			Expires:  expires,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/"+hash, http.StatusSeeOther)
+	}
+}
+
+func (handler *LinkHandler) isUnlocked(r *http.Request, link *Link) bool {
+	cookie, err := r.Cookie(unlockCookieName(link.Hash))
+	if err != nil {
+		return false
+	}
+	return verifyUnlock(handler.Config.Auth.Secret, link, cookie.Value, time.Now())
+}
+
+func renderUnlockPage(w http.ResponseWriter, hash, errMessage string, statusCode int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	unlockPage.Execute(w, unlockPageData{
+		Alias: hash,
+		Error: errMessage,
+	})
+}
+
 func (handler *LinkHandler) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-    limitStr := r.URL.Query().Get("limit")
-    offsetStr := r.URL.Query().Get("offset")
-
-    limit := 10
-    offset := 0
-
-    if limitStr != "" {
-        l, err := strconv.Atoi(limitStr)
-        if err != nil || l < 0 {
-            http.Error(w, "Error with parsing limit", http.StatusBadRequest)
-            return
-        }
-        limit = l
-    }
-
-    if offsetStr != "" {
-        o, err := strconv.Atoi(offsetStr)
-        if err != nil || o < 0 {
-            http.Error(w, "Error with parsing offset", http.StatusBadRequest)
-            return
-        }
-        offset = o
-    }
-
-    links, count := handler.LinkService.GetAll(limit, offset)
-
-    res.Json(w, &GetAllLinksResponse{
-        Links: links,
-        Count: count,
-    }, http.StatusOK)
+		page, err := cursor.ParsePage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query, err := parseLinkQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if page.Cursor != nil && page.Cursor.Sort != query.SortID() {
+			http.Error(w, cursor.ErrInvalid, http.StatusBadRequest)
+			return
+		}
+		query.Page = page
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		response, err := handler.LinkService.GetAll(email, query)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, response, http.StatusOK)
+	}
+}
+
+// Export streams links visible to the user as CSV or JSON Lines, filtered like GET /link.
+func (handler *LinkHandler) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := export.Negotiate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query, err := parseLinkQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.LinkService.PrepareExport(email, query); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writer, err := export.NewWriter(w, format, "links", linkExportHeader)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Finish(handler.LinkService.Export(query, func(row *LinkExport) error {
+			return writer.Write(row)
+		}))
+	}
+}
+
+// parseLinkQuery reads filters of link listing: workspace_id, owner (user id)
+// and from/to (YYYY-MM-DD, both inclusive) of the creation date.
+func parseLinkQuery(r *http.Request) (*LinkQuery, error) {
+	params := r.URL.Query()
+	query := &LinkQuery{}
+
+	parseID := func(name string) (*uint, error) {
+		value := params.Get(name)
+		if value == "" {
+			return nil, nil
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, errors.New("Error with parsing " + name)
+		}
+		n := uint(id)
+		return &n, nil
+	}
+	var err error
+	if query.WorkspaceID, err = parseID("workspace_id"); err != nil {
+		return nil, err
+	}
+	if query.OwnerID, err = parseID("owner"); err != nil {
+		return nil, err
+	}
+	if query.FolderID, err = parseID("folder_id"); err != nil {
+		return nil, err
+	}
+
+	if query.CreatedFrom, query.CreatedTo, err = req.DateRange(params); err != nil {
+		return nil, err
+	}
+
+	query.Search = strings.TrimSpace(params.Get("q"))
+	query.Tag = strings.ToLower(strings.TrimSpace(params.Get("tag")))
+	switch query.Status = params.Get("status"); query.Status {
+	case "", LinkStatusActive, LinkStatusExpired:
+	default:
+		return nil, errors.New("status must be active or expired")
+	}
+	switch query.Sort = params.Get("sort"); query.Sort {
+	case "", LinkSortCreatedAt, LinkSortClicks, LinkSortLastVisited:
+	default:
+		return nil, errors.New("sort must be created_at, clicks or last_visited")
+	}
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return nil, errors.New("order must be asc or desc")
+	}
+	return query, nil
+}
+
+// parseStatsQuery reads from/to (YYYY-MM-DD, both inclusive, last 30 days by default),
+// by (hour, day, week or month) and top from the query string.
+func parseStatsQuery(r *http.Request) (*stat.LinkStatsQuery, error) {
+	params := r.URL.Query()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	query := &stat.LinkStatsQuery{
+		From: today.AddDate(0, 0, -29),
+		To:   today.AddDate(0, 0, 1),
+		By:   stat.GroupByDay,
+		Top:  10,
+	}
+
+	from, to, err := req.DateRange(params)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil {
+		query.From = *from
+	}
+	if to != nil {
+		query.To = *to
+	}
+
+	if by := params.Get("by"); by != "" {
+		switch by {
+		case stat.GroupByHour, stat.GroupByDay, stat.GroupByWeek, stat.GroupByMonth:
+			query.By = by
+		default:
+			return nil, errors.New("Error with parse by param")
+		}
+	}
+	if top := params.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n <= 0 || n > 100 {
+			return nil, errors.New("Error with parse top param")
+		}
+		query.Top = n
+	}
+
+	return query, nil
+}
+
+// errorStatus maps LinkService errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch err.Error() {
+	case ErrLinkNotFound, ErrFolderNotFound:
+		return http.StatusNotFound
+	case ErrLinkExpired:
+		return http.StatusGone
+	case ErrLinkForbidden, ErrWorkspaceForbidden:
+		return http.StatusForbidden
+	case ErrUserNotFound:
+		return http.StatusUnauthorized
+	case ErrAliasInUse:
+		return http.StatusConflict
+	case ErrDomainInvalid, ErrFolderInvalid, ErrRuleInvalid:
+		return http.StatusUnprocessableEntity
 	}
+	return http.StatusBadRequest
 }
@@ -2,26 +2,95 @@ package link
 
 import (
 	"math/rand"
+	"net/http"
+	"time"
 	"url/short/internal/stat"
+	"url/short/internal/user"
+	"url/short/pkg/cursor"
+	"url/short/pkg/utm"
 
 	"gorm.io/gorm"
 )
 
 type Link struct {
 	gorm.Model
-	Url   string      `json:"url"`
-	Hash  string      `json:"hash" gorm:"uniqueIndex"`
-	Stats []stat.Stat `json:"stats" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Url    string      `json:"url"`
+	Hash   string      `json:"hash" gorm:"uniqueIndex:idx_links_domain_alias,priority:2,where:deleted_at IS NULL"`
+	UserID uint        `json:"user_id" gorm:"index"`
+	User   *user.User  `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Stats  []stat.Stat `json:"stats" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+
+	// WorkspaceID shares the link with members of the workspace, nil for personal links.
+	WorkspaceID *uint           `json:"workspace_id" gorm:"index"`
+	Workspace   *user.Workspace `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// DomainID is the custom domain the alias lives on, 0 for the service's own host.
+	DomainID uint  `json:"domain_id" gorm:"not null;default:0;uniqueIndex:idx_links_domain_alias,priority:1"`
+	Tags     []Tag `json:"tags" gorm:"many2many:link_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *uint      `json:"max_clicks"`
+	// ClicksUsed is counted only for links with MaxClicks.
+	ClicksUsed uint `json:"clicks_used" gorm:"not null;default:0"`
+	// Password is a bcrypt hash, empty for public links.
+	Password string `json:"-"`
+	// RedirectType is the status GET /{alias} replies with, 0 uses the server default.
+	RedirectType int `json:"redirect_type"`
+	// ForwardQuery adds the query of GET /{alias} to the url.
+	ForwardQuery bool `json:"forward_query"`
+	// UTM parameters are added to the url at redirect.
+	UTM utm.Params `json:"utm" gorm:"embedded;embeddedPrefix:utm_"`
+	// Rules may send a visit to another url, they are loaded only for redirects.
+	Rules []Rule `json:"rules,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	FolderID    *uint   `json:"folder_id" gorm:"index"`
+	Folder      *Folder `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// ImageUrl and FaviconUrl come from the target page, fetched after the link is created.
+	ImageUrl    string     `json:"image_url"`
+	FaviconUrl  string     `json:"favicon_url"`
+	PreviewedAt *time.Time `json:"previewed_at"`
+
+	// SortKey is the value the listing is ordered by, read only for cursors.
+	SortKey string `json:"-" gorm:"->;-:migration"`
 }
 
-func NewLink(url string) *Link {
+func (link *Link) cursor(sort string) *cursor.Cursor {
+	return &cursor.Cursor{Sort: sort, Key: link.SortKey, ID: link.ID}
+}
+
+func NewLink(url string, userID uint) *Link {
 	link := &Link{
-		Url: url,
+		Url:    url,
+		UserID: userID,
 	}
 	link.generateHash()
 	return link
 }
 
+// IsExpired reports whether the link outlived its expiration date or click limit.
+func (link *Link) IsExpired(now time.Time) bool {
+	if link.ExpiresAt != nil && !now.Before(*link.ExpiresAt) {
+		return true
+	}
+	return link.MaxClicks != nil && link.ClicksUsed >= *link.MaxClicks
+}
+
+// RedirectStatus returns the status to redirect with, fallback for links without their own.
+func (link *Link) RedirectStatus(fallback int) int {
+	if link.RedirectType != 0 {
+		return link.RedirectType
+	}
+	if fallback != 0 {
+		return fallback
+	}
+	return http.StatusTemporaryRedirect
+}
+
+func (link *Link) IsProtected() bool {
+	return link.Password != ""
+}
+
 func (link *Link) generateHash() {
 	link.Hash = RandStringRunes(6)
 }
@@ -0,0 +1,26 @@
+package link
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+func TestCountersAreNotNull(t *testing.T) {
+	s, err := schema.Parse(&Link{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// existing rows must get 0 when the column is added, NULL never matches lookups
+	for _, name := range []string{"DomainID", "ClicksUsed"} {
+		field := s.LookUpField(name)
+		if field == nil {
+			t.Fatalf("expected field %s", name)
+		}
+		if !field.NotNull || field.DefaultValue != "0" {
+			t.Fatalf("%s: expected NOT NULL DEFAULT 0, got not null %v default %q", name, field.NotNull, field.DefaultValue)
+		}
+	}
+}
@@ -1,15 +1,156 @@
 package link
 
+import (
+	"net/url"
+	"time"
+	"url/short/pkg/cursor"
+	"url/short/pkg/utm"
+)
+
 type LinkCreateRequest struct {
-	Url string `json:"url" validate:"required,url"`
+	Url       string     `json:"url" validate:"required,url"`
+	Alias     string     `json:"alias"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *uint      `json:"max_clicks" validate:"omitempty,min=1"`
+	Password  string     `json:"password" validate:"omitempty,min=4"`
+	// WorkspaceID creates the link in a workspace the user is a member of.
+	WorkspaceID *uint `json:"workspace_id"`
+	// Domain is a verified custom domain of the workspace to create the alias on.
+	Domain string   `json:"domain"`
+	Tags   []string `json:"tags" validate:"max=20,dive,max=32"`
+
+	Title       string `json:"title" validate:"max=200"`
+	Description string `json:"description" validate:"max=1000"`
+	FolderID    *uint  `json:"folder_id"`
+	// RedirectType is 301, 302, 307 or 308, the server default when omitted.
+	RedirectType int        `json:"redirect_type" validate:"omitempty,oneof=301 302 307 308"`
+	ForwardQuery bool       `json:"forward_query"`
+	UTM          utm.Params `json:"utm"`
+	// Rules are tried in order before redirecting to Url.
+	Rules []RuleRequest `json:"rules" validate:"max=20,dive"`
 }
 
 type LinkUpdateRequest struct {
-	Url  string `json:"url" validate:"required,url"`
-	Hash string `json:"hash"`
+	Url       string     `json:"url" validate:"required,url"`
+	Hash      string     `json:"hash"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *uint      `json:"max_clicks" validate:"omitempty,min=1"`
+	Password  string     `json:"password" validate:"omitempty,min=4"`
+
+	Title       string `json:"title" validate:"max=200"`
+	Description string `json:"description" validate:"max=1000"`
+	// FolderID moves the link into the folder, 0 takes it out of its folder.
+	FolderID *uint `json:"folder_id"`
+	// RedirectType changes the status of redirects, 0 returns to the server default.
+	RedirectType *int  `json:"redirect_type" validate:"omitempty,oneof=0 301 302 307 308"`
+	ForwardQuery *bool `json:"forward_query"`
+	// UTM replaces all UTM parameters of the link, empty ones are removed.
+	UTM *utm.Params `json:"utm"`
+	// Clear empties the listed fields, over values given for them.
+	Clear []string `json:"clear" validate:"max=5,dive,oneof=expires_at max_clicks password title description"`
+}
+
+// RuleRequest describes a redirect rule, a visit matching all given conditions goes to Url.
+type RuleRequest struct {
+	// ID of a rule of the link updates it in place, so its clicks stay attributed to it.
+	ID        uint       `json:"id"`
+	Url       string     `json:"url" validate:"required,url"`
+	OS        []string   `json:"os" validate:"max=10,dive,oneof=Windows iOS Android ChromeOS macOS Linux Other"`
+	Devices   []string   `json:"devices" validate:"max=4,dive,oneof=desktop mobile tablet bot"`
+	Countries []string   `json:"countries" validate:"max=50,dive,len=2,alpha"`
+	Languages []string   `json:"languages" validate:"max=20,dive,min=2,max=35"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at" validate:"omitempty,gtfield=StartsAt"`
+}
+
+type LinkRulesRequest struct {
+	Rules []RuleRequest `json:"rules" validate:"max=20,dive"`
+}
+
+type LinkTagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=20,dive,max=32"`
+}
+
+type FolderCreateRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+	// WorkspaceID creates the folder in a workspace the user is a member of.
+	WorkspaceID *uint `json:"workspace_id"`
+}
+
+// VisitRequest describes a single request to GET /{alias}.
+type VisitRequest struct {
+	Host      string
+	Alias     string
+	IP        string
+	UserAgent string
+	Referrer  string
+	Source    string
+	// Unlocked reports whether the visitor may open the protected link, nil keeps it locked.
+	Unlocked func(link *Link) bool
+	// Query of the request is forwarded to the url by links with ForwardQuery.
+	Query url.Values
+	// Language is the Accept-Language header matched by rules.
+	Language string
+}
+
+// LinkQuery selects links visible to the user: their own links and
+// links of their workspaces, or only links of WorkspaceID when set.
+// All selects links of every user and is set only for admins.
+type LinkQuery struct {
+	UserID      uint
+	All         bool
+	WorkspaceID *uint
+	OwnerID     *uint
+	FolderID    *uint
+	// CreatedFrom and CreatedTo limit creation time, CreatedTo is exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Search is a case-insensitive substring of the url or alias.
+	Search string
+	Tag    string
+	Status string
+	Sort   string
+	Desc   bool
+	// Page is nil for exports.
+	Page *cursor.Page
+}
+
+// SortID names the order of the query in its cursors.
+func (query *LinkQuery) SortID() string {
+	if query.Desc {
+		return query.Sort + " desc"
+	}
+	return query.Sort + " asc"
+}
+
+const (
+	LinkStatusActive  = "active"
+	LinkStatusExpired = "expired"
+)
+
+const (
+	LinkSortCreatedAt   = "created_at"
+	LinkSortClicks      = "clicks"
+	LinkSortLastVisited = "last_visited"
+)
+
+// BulkLinkResult reports the outcome of one row of POST /link/bulk, rows are numbered from 1.
+type BulkLinkResult struct {
+	Row   int    `json:"row"`
+	Link  *Link  `json:"link,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type BulkLinkResponse struct {
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Results []BulkLinkResult `json:"results"`
 }
 
 type GetAllLinksResponse struct {
 	Links []Link `json:"links"`
-	Count int64  `json:"count"`
+	// Count is set only when requested with count=true.
+	Count      *int64 `json:"count,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
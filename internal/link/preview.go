@@ -0,0 +1,100 @@
+package link
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"url/short/configs"
+	"url/short/pkg/event"
+	"url/short/pkg/metadata"
+)
+
+type IPreviewRepository interface {
+	SetPreview(id uint, meta *metadata.Metadata) error
+}
+
+type PreviewServiceDeps struct {
+	EventBus       *event.EventBus
+	LinkRepository IPreviewRepository
+	Fetcher        metadata.Fetcher
+	Config         *configs.Config
+}
+
+// PreviewService fetches metadata of pages new links point to in the background:
+// it subscribes to created links with a buffer of Preview.BufferSize and a pool
+// of workers stores title, description, image and favicon on the links.
+// Links created while the buffer is full or still queued on Close stay without a preview.
+type PreviewService struct {
+	LinkRepository IPreviewRepository
+	Fetcher        metadata.Fetcher
+
+	created *event.Subscription[event.LinkCreated]
+	workers int
+	timeout time.Duration
+
+	// ctx is cancelled by Close to stop the workers and abort their fetches.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPreviewService(deps *PreviewServiceDeps) *PreviewService {
+	conf := deps.Config.Preview
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PreviewService{
+		LinkRepository: deps.LinkRepository,
+		Fetcher:        deps.Fetcher,
+		created:        event.Subscribe[event.LinkCreated](deps.EventBus, conf.BufferSize),
+		workers:        conf.Workers,
+		timeout:        conf.Timeout,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Start launches the worker pool.
+func (s *PreviewService) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+}
+
+// Close unsubscribes from created links, cancels the fetches in progress
+// and waits for the workers to return. Queued links are not fetched.
+func (s *PreviewService) Close() {
+	s.cancel()
+	s.created.Unsubscribe()
+	s.wg.Wait()
+}
+
+func (s *PreviewService) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case created, ok := <-s.created.Events():
+			// select picks at random when both are ready
+			if !ok || s.ctx.Err() != nil {
+				return
+			}
+			if err := s.preview(created); err != nil && s.ctx.Err() == nil {
+				log.Println("Error fetching preview of link", created.LinkID, err)
+			}
+		}
+	}
+}
+
+func (s *PreviewService) preview(created event.LinkCreated) error {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+
+	meta, err := s.Fetcher.Fetch(ctx, created.Url)
+	if err != nil {
+		return err
+	}
+	return s.LinkRepository.SetPreview(created.LinkID, meta)
+}
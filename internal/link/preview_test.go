@@ -0,0 +1,100 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"url/short/configs"
+	"url/short/pkg/event"
+	"url/short/pkg/metadata"
+)
+
+type MockFetcher struct {
+}
+
+func (m *MockFetcher) Fetch(ctx context.Context, rawURL string) (*metadata.Metadata, error) {
+	if rawURL == "https://down.example.com" {
+		return nil, errors.New("connection refused")
+	}
+	if rawURL == "https://slow.example.com" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &metadata.Metadata{Title: "Title of " + rawURL}, nil
+}
+
+type MockPreviewRepository struct {
+	mu       sync.Mutex
+	previews map[uint]*metadata.Metadata
+}
+
+func (m *MockPreviewRepository) SetPreview(id uint, meta *metadata.Metadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.previews[id] = meta
+	return nil
+}
+
+func (m *MockPreviewRepository) get(id uint) *metadata.Metadata {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.previews[id]
+}
+
+func TestPreviewServiceStoresMetadata(t *testing.T) {
+	bus := event.NewEventBus()
+	repo := &MockPreviewRepository{previews: map[uint]*metadata.Metadata{}}
+	service := NewPreviewService(&PreviewServiceDeps{
+		EventBus:       bus,
+		LinkRepository: repo,
+		Fetcher:        &MockFetcher{},
+		Config:         &configs.Config{Preview: configs.Previewconfig{Timeout: time.Second, BufferSize: 10, Workers: 2}},
+	})
+	service.Start()
+
+	bus.Publish(event.LinkCreated{LinkID: 1, Url: "https://go.dev"})
+	bus.Publish(event.LinkCreated{LinkID: 2, Url: "https://down.example.com"})
+	bus.Publish(event.LinkCreated{LinkID: 3, Url: "https://go.dev/doc"})
+	// Close drops queued links, wait for the last one to be stored
+	deadline := time.Now().Add(time.Second)
+	for repo.get(3) == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	service.Close()
+
+	if len(repo.previews) != 2 || repo.previews[1] == nil || repo.previews[1].Title != "Title of https://go.dev" {
+		t.Fatalf("Expected previews of links 1 and 3, got %+v", repo.previews)
+	}
+}
+
+func TestPreviewServiceCloseCancelsFetches(t *testing.T) {
+	bus := event.NewEventBus()
+	repo := &MockPreviewRepository{previews: map[uint]*metadata.Metadata{}}
+	service := NewPreviewService(&PreviewServiceDeps{
+		EventBus:       bus,
+		LinkRepository: repo,
+		Fetcher:        &MockFetcher{},
+		Config:         &configs.Config{Preview: configs.Previewconfig{Timeout: time.Minute, BufferSize: 10, Workers: 1}},
+	})
+	service.Start()
+
+	bus.Publish(event.LinkCreated{LinkID: 1, Url: "https://slow.example.com"})
+	bus.Publish(event.LinkCreated{LinkID: 2, Url: "https://go.dev"})
+
+	done := make(chan struct{})
+	go func() {
+		service.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close to cancel the fetch in progress")
+	}
+
+	if len(repo.previews) != 0 {
+		t.Fatalf("Expected queued link to be skipped, got %+v", repo.previews)
+	}
+}
@@ -0,0 +1,157 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"url/short/pkg/qr"
+)
+
+// SourceQR marks visits made by scanning a QR code, the code points to the alias with ?src=qr.
+const SourceQR = "qr"
+
+// qrSuffix serves the code of an alias at GET /{alias}.qr, aliases can't contain a dot.
+const qrSuffix = ".qr"
+
+const (
+	qrDefaultSize = 256
+	qrMaxSize     = 2048
+	qrMaxMargin   = 20
+)
+
+type qrRequest struct {
+	svg   bool
+	level qr.Level
+	opts  qr.RenderOptions
+}
+
+// parseQR reads format (png or svg, otherwise by the Accept header), size in pixels,
+// level (L, M, Q, H), margin in modules and fg/bg hex colors.
+func parseQR(r *http.Request) (*qrRequest, error) {
+	params := r.URL.Query()
+	request := &qrRequest{
+		level: qr.Medium,
+		opts: qr.RenderOptions{
+			Size:       qrDefaultSize,
+			Margin:     4,
+			Foreground: qr.Black,
+			Background: qr.White,
+		},
+	}
+
+	switch format := strings.ToLower(params.Get("format")); format {
+	case "svg":
+		request.svg = true
+	case "png":
+	case "":
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+			if mediaType == "image/svg+xml" {
+				request.svg = true
+				break
+			}
+			if mediaType == "image/png" {
+				break
+			}
+		}
+	default:
+		return nil, errors.New("format must be png or svg")
+	}
+
+	if size := params.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 32 || n > qrMaxSize {
+			return nil, errors.New("size must be from 32 to 2048 pixels")
+		}
+		request.opts.Size = n
+	}
+	if margin := params.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > qrMaxMargin {
+			return nil, errors.New("margin must be from 0 to 20 modules")
+		}
+		request.opts.Margin = n
+	}
+	if level := params.Get("level"); level != "" {
+		l, err := qr.ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		request.level = l
+	}
+	var err error
+	if fg := params.Get("fg"); fg != "" {
+		if request.opts.Foreground, err = qr.ParseColor(fg); err != nil {
+			return nil, err
+		}
+	}
+	if bg := params.Get("bg"); bg != "" {
+		if request.opts.Background, err = qr.ParseColor(bg); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// shortURL is the address of the link: on its custom domain, on BASE_URL
+// or, without it, on the host the request came to.
+func (handler *LinkHandler) shortURL(r *http.Request, link *Link) (string, error) {
+	host, err := handler.LinkService.Host(link)
+	if err != nil {
+		return "", err
+	}
+	base := "https://" + host
+	if host == "" {
+		base = handler.Config.Link.BaseURL
+	}
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/" + url.PathEscape(link.Hash), nil
+}
+
+// writeQR renders the code of the link's short URL marked as a QR scan.
+func (handler *LinkHandler) writeQR(w http.ResponseWriter, r *http.Request, link *Link) {
+	request, err := parseQR(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := handler.shortURL(r, link)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	code, err := qr.Encode([]byte(target+"?src="+SourceQR), request.level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if request.svg {
+		contentType = "image/svg+xml"
+		err = code.SVG(&buf, request.opts)
+	} else {
+		err = code.PNG(&buf, request.opts)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
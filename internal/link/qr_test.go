@@ -0,0 +1,79 @@
+package link
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url/short/configs"
+	"url/short/pkg/event"
+	"url/short/pkg/qr"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseQR(t *testing.T) {
+	r := httptest.NewRequest("GET", "/promo.qr?size=512&level=h&margin=2&fg=%23ff0000", nil)
+	r.Header.Set("Accept", "image/svg+xml")
+
+	request, err := parseQR(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !request.svg || request.level != qr.High || request.opts.Size != 512 || request.opts.Margin != 2 {
+		t.Fatalf("Unexpected options %+v", request)
+	}
+	if request.opts.Foreground.R != 0xFF || request.opts.Background != qr.White {
+		t.Fatalf("Unexpected colors %+v", request.opts)
+	}
+
+	for _, query := range []string{"size=10", "size=big", "margin=-1", "level=X", "fg=red", "format=gif"} {
+		if _, err := parseQR(httptest.NewRequest("GET", "/promo.qr?"+query, nil)); err == nil {
+			t.Fatalf("%s: expected error", query)
+		}
+	}
+}
+
+func TestAliasQRAndScanSource(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := http.NewServeMux()
+	NewLinkHandler(router, LinkHandlerDeps{
+		LinkService: service,
+		Config:      &configs.Config{Link: configs.Linkconfig{BaseURL: "https://sho.rt"}},
+	})
+	visits := event.Subscribe[event.LinkVisited](service.eventBus, 1)
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "url", "hash"}).AddRow(5, "https://go.dev", "promo")
+	}
+
+	mock.ExpectQuery("SELECT").WithArgs(0, "promo", 1).WillReturnRows(rows())
+	expectNoRules(mock)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/promo.qr?format=svg", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("Expected svg, got %d %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Body.String(), "<svg") {
+		t.Fatalf("Expected svg document, got %.50s", w.Body.String())
+	}
+	if visits.Len() != 0 {
+		t.Fatal("Rendering a code must not count a visit")
+	}
+
+	mock.ExpectQuery("SELECT").WithArgs(0, "promo", 1).WillReturnRows(rows())
+	expectNoRules(mock)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/promo?src=qr", nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Expected redirect, got %d", w.Code)
+	}
+	if visit := <-visits.Events(); visit.Source != SourceQR {
+		t.Fatalf("Expected source %q, got %q", SourceQR, visit.Source)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
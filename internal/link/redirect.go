@@ -0,0 +1,39 @@
+package link
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// redirect sends the visitor to the target url of the link with the link's redirect status.
+func (handler *LinkHandler) redirect(w http.ResponseWriter, r *http.Request, link *Link, target string) {
+	status := link.RedirectStatus(handler.Config.Link.RedirectType)
+	if isPermanent(status) {
+		w.Header().Set("Cache-Control", permanentCacheControl(link, handler.Config.Link.RedirectMaxAge, time.Now()))
+	}
+	http.Redirect(w, r, target, status)
+}
+
+func isPermanent(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
+// permanentCacheControl bounds caching of a permanent redirect, which browsers otherwise
+// keep forever. Cached redirects skip the service, so links with a click limit or rules,
+// whose target depends on the visitor, are revalidated every time, expiring links are
+// cached until they expire and protected links are cached only by the browser that unlocked them.
+func permanentCacheControl(link *Link, maxAge time.Duration, now time.Time) string {
+	scope := "public"
+	if link.IsProtected() || len(link.Rules) > 0 {
+		scope = "private"
+	}
+	if link.MaxClicks != nil || len(link.Rules) > 0 {
+		return scope + ", no-cache"
+	}
+	if link.ExpiresAt != nil {
+		maxAge = min(maxAge, link.ExpiresAt.Sub(now))
+	}
+	seconds := max(int(maxAge/time.Second), 0)
+	return scope + ", max-age=" + strconv.Itoa(seconds)
+}
@@ -0,0 +1,88 @@
+package link
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url/short/configs"
+	"url/short/pkg/req"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGoToRedirectType(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := http.NewServeMux()
+	NewLinkHandler(router, LinkHandlerDeps{
+		LinkService: service,
+		Config: &configs.Config{Link: configs.Linkconfig{
+			RedirectType:   http.StatusFound,
+			RedirectMaxAge: time.Hour,
+		}},
+	})
+	cases := []struct {
+		redirectType int
+		status       int
+		cacheControl string
+	}{
+		{0, http.StatusFound, ""},
+		{http.StatusTemporaryRedirect, http.StatusTemporaryRedirect, ""},
+		{http.StatusMovedPermanently, http.StatusMovedPermanently, "public, max-age=3600"},
+		{http.StatusPermanentRedirect, http.StatusPermanentRedirect, "public, max-age=3600"},
+	}
+
+	for _, c := range cases {
+		rows := sqlmock.NewRows([]string{"id", "url", "hash", "redirect_type"}).AddRow(5, "https://go.dev", "promo", c.redirectType)
+		mock.ExpectQuery("SELECT").WillReturnRows(rows)
+		expectNoRules(mock)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/promo", nil))
+
+		if w.Code != c.status || w.Header().Get("Location") != "https://go.dev" {
+			t.Fatalf("%d: expected %d to https://go.dev, got %d to %s", c.redirectType, c.status, w.Code, w.Header().Get("Location"))
+		}
+		if got := w.Header().Get("Cache-Control"); got != c.cacheControl {
+			t.Fatalf("%d: expected Cache-Control %q, got %q", c.redirectType, c.cacheControl, got)
+		}
+	}
+}
+
+func TestPermanentCacheControl(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(10 * time.Minute)
+	maxClicks := uint(5)
+	cases := []struct {
+		link *Link
+		want string
+	}{
+		{&Link{}, "public, max-age=3600"},
+		{&Link{ExpiresAt: &expiresAt}, "public, max-age=600"},
+		{&Link{MaxClicks: &maxClicks}, "public, no-cache"},
+		{&Link{Password: "hash"}, "private, max-age=3600"},
+		{&Link{Rules: []Rule{{Url: "https://go.dev/ru"}}}, "private, no-cache"},
+	}
+	for _, c := range cases {
+		if got := permanentCacheControl(c.link, time.Hour, now); got != c.want {
+			t.Fatalf("Expected %q, got %q", c.want, got)
+		}
+	}
+}
+
+func TestRedirectTypeValidation(t *testing.T) {
+	for _, status := range []int{0, 301, 302, 307, 308} {
+		if err := req.IsValid(LinkUpdateRequest{Url: "https://go.dev", RedirectType: &status}); err != nil {
+			t.Fatalf("%d: %v", status, err)
+		}
+	}
+	status := http.StatusSeeOther
+	if err := req.IsValid(LinkUpdateRequest{Url: "https://go.dev", RedirectType: &status}); err == nil {
+		t.Fatal("Expected 303 to be rejected")
+	}
+	if err := req.IsValid(LinkCreateRequest{Url: "https://go.dev", RedirectType: status}); err == nil {
+		t.Fatal("Expected 303 to be rejected")
+	}
+}
@@ -1,8 +1,14 @@
 package link
 
 import (
+	"errors"
+	"strings"
+	"time"
+	"url/short/pkg/cursor"
 	"url/short/pkg/db"
+	"url/short/pkg/metadata"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -25,9 +31,82 @@ func (repo *LinkRepository) Create(link *Link) (*Link, error) {
 	return link, nil
 }
 
-func (repo *LinkRepository) GetByHash(hash string) (*Link, error) {
+// Transaction runs fn with a repository bound to a single transaction.
+func (repo *LinkRepository) Transaction(fn func(tx *LinkRepository) error) error {
+	return repo.DataBase.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewLinkRepository(&db.DB{DB: tx}))
+	})
+}
+
+// GetTags returns tags with given names, creating missing ones.
+func (repo *LinkRepository) GetTags(names []string) ([]Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, Tag{Name: name})
+	}
+	err := repo.DataBase.DB.
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tags = tags[:0]
+	if err := repo.DataBase.DB.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(names) {
+		return nil, errors.New("failed to create tags")
+	}
+	return tags, nil
+}
+
+// AddTags labels the link with tags it doesn't have yet.
+func (repo *LinkRepository) AddTags(link *Link, tags []Tag) error {
+	return repo.DataBase.DB.Model(link).Association("Tags").Append(tags)
+}
+
+// RemoveTag removes the label from the link, the tag itself stays.
+func (repo *LinkRepository) RemoveTag(linkID uint, name string) error {
+	return repo.DataBase.DB.
+		Exec("DELETE FROM link_tags WHERE link_id = ? AND tag_id IN (SELECT id FROM tags WHERE name = ?)", linkID, name).Error
+}
+
+// GetTagCounts returns tags of the links matching the query with the number of links of each.
+func (repo *LinkRepository) GetTagCounts(query *LinkQuery) []TagCount {
+	var tags []TagCount
+	repo.filter(query).
+		Joins("JOIN link_tags ON link_tags.link_id = links.id").
+		Joins("JOIN tags ON tags.id = link_tags.tag_id").
+		Select("tags.name AS name, COUNT(*) AS links").
+		Group("tags.name").
+		Order("tags.name ASC").
+		Scan(&tags)
+	return tags
+}
+
+// GetByHash finds the link by alias on the domain, 0 is the service's own host.
+func (repo *LinkRepository) GetByHash(domainID uint, hash string) (*Link, error) {
+	var link Link
+	result := repo.DataBase.DB.First(&link, "domain_id = ? AND hash = ?", domainID, hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &link, nil
+}
+
+// GetByHashWithRules finds the link like GetByHash along with its redirect rules in order.
+func (repo *LinkRepository) GetByHashWithRules(domainID uint, hash string) (*Link, error) {
 	var link Link
-	result := repo.DataBase.DB.First(&link, "hash = ?", hash)
+	result := repo.DataBase.DB.
+		Preload("Rules", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("position ASC")
+		}).
+		First(&link, "domain_id = ? AND hash = ?", domainID, hash)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -35,8 +114,10 @@ func (repo *LinkRepository) GetByHash(hash string) (*Link, error) {
 	return &link, nil
 }
 
-func (repo *LinkRepository) Update(link *Link) (*Link, error) {
-	result := repo.DataBase.DB.Clauses(clause.Returning{}).Updates(link)
+// Update sets the columns of the link, nil values clear nullable ones.
+func (repo *LinkRepository) Update(id uint, fields map[string]any) (*Link, error) {
+	link := &Link{Model: gorm.Model{ID: id}}
+	result := repo.DataBase.DB.Model(link).Clauses(clause.Returning{}).Updates(fields)
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -55,6 +136,107 @@ func (repo *LinkRepository) Delete(id uint) error {
 	return nil
 }
 
+// SetPreview stores metadata of the target page. Title and description
+// are filled only when the user left them empty.
+func (repo *LinkRepository) SetPreview(id uint, meta *metadata.Metadata) error {
+	return repo.DataBase.DB.Model(&Link{}).Where("id = ?", id).Updates(map[string]any{
+		"title":        gorm.Expr("CASE WHEN title = '' THEN ? ELSE title END", meta.Title),
+		"description":  gorm.Expr("CASE WHEN description = '' THEN ? ELSE description END", meta.Description),
+		"image_url":    meta.Image,
+		"favicon_url":  meta.Favicon,
+		"previewed_at": time.Now(),
+	}).Error
+}
+
+func (repo *LinkRepository) GetRules(linkID uint) []Rule {
+	var rules []Rule
+	repo.DataBase.DB.Where("link_id = ?", linkID).Order("position ASC").Find(&rules)
+	return rules
+}
+
+// SetRules replaces the rules of the link. Rules with an id are updated in place,
+// the rest are created and rules left out are deleted.
+func (repo *LinkRepository) SetRules(linkID uint, rules []Rule) error {
+	return repo.DataBase.DB.Transaction(func(tx *gorm.DB) error {
+		kept := make([]uint, 0, len(rules))
+		for i := range rules {
+			rules[i].LinkID = linkID
+			if rules[i].ID != 0 {
+				kept = append(kept, rules[i].ID)
+			}
+		}
+
+		removed := tx.Where("link_id = ?", linkID)
+		if len(kept) > 0 {
+			removed = removed.Where("id NOT IN ?", kept)
+		}
+		if err := removed.Delete(&Rule{}).Error; err != nil {
+			return err
+		}
+		for i := range rules {
+			if rules[i].ID == 0 {
+				if err := tx.Create(&rules[i]).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			result := tx.Select("*").Omit("created_at", "deleted_at").
+				Where("link_id = ?", linkID).
+				Updates(&rules[i])
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+func (repo *LinkRepository) CreateFolder(folder *Folder) (*Folder, error) {
+	result := repo.DataBase.DB.Create(folder)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return folder, nil
+}
+
+func (repo *LinkRepository) GetFolder(id uint) (*Folder, error) {
+	var folder Folder
+	result := repo.DataBase.DB.First(&folder, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &folder, nil
+}
+
+// GetFolders returns personal folders of the user and folders of their workspaces,
+// or only folders of workspaceID when set.
+func (repo *LinkRepository) GetFolders(userID uint, workspaceID *uint) []Folder {
+	var folders []Folder
+	tx := repo.DataBase.DB.Order("name ASC, id ASC")
+	if workspaceID != nil {
+		tx = tx.Where("workspace_id = ?", *workspaceID)
+	} else {
+		tx = tx.Where("(user_id = ? AND workspace_id IS NULL) OR workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ?)", userID, userID)
+	}
+	tx.Find(&folders)
+	return folders
+}
+
+// DeleteFolder removes the folder for good, its links stay without a folder.
+func (repo *LinkRepository) DeleteFolder(id uint) error {
+	return repo.DataBase.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Link{}).Where("folder_id = ?", id).Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Folder{}, id).Error
+	})
+}
+
 func (repo *LinkRepository) GetById(id uint) (*Link, error) {
 	var link Link
 	result := repo.DataBase.DB.First(&link, id)
@@ -65,24 +247,168 @@ func (repo *LinkRepository) GetById(id uint) (*Link, error) {
 	return &link, nil
 }
 
-func (repo *LinkRepository) Count() int64 {
+// UseClick atomically counts a visit against the link's click limit.
+// It returns false when the limit is already exhausted.
+func (repo *LinkRepository) UseClick(id uint) (bool, error) {
+	result := repo.DataBase.DB.Model(&Link{}).
+		Where("id = ? AND (max_clicks IS NULL OR clicks_used < max_clicks)", id).
+		UpdateColumn("clicks_used", gorm.Expr("clicks_used + 1"))
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (repo *LinkRepository) Count(query *LinkQuery) int64 {
 	var count int64
-	repo.DataBase.
-		Table("links").
-		Where("deleted_at IS NULL").
-		Count(&count)
+	repo.filter(query).Count(&count)
 	return count
 }
 
-func (repo *LinkRepository) Get(limit, offset int) []Link {
+// Get returns a page of links. With a cursor it fetches up to Limit+1 links
+// after the cursor, or before it in reverse order, for cursor.Paginate.
+func (repo *LinkRepository) Get(query *LinkQuery) []Link {
 	var links []Link
 
-	repo.DataBase.
-		Table("links").
-		Where("deleted_at IS NULL").
-		Order("id ASC").
-		Limit(limit).
-		Offset(offset).
+	page := query.Page
+	sort := linkSortOf(query.Sort, query.Desc)
+	desc := query.Desc
+	tx := repo.filter(query)
+	if page.Cursor != nil {
+		if page.Cursor.Before {
+			desc = !desc
+		}
+		tx = sort.seek(tx, page.Cursor, desc).Limit(page.Limit + 1)
+	} else {
+		tx = tx.Limit(page.Limit + 1).Offset(page.Offset)
+	}
+	tx.Preload("Tags").
+		Select("links.*, CAST(" + sort.expr + " AS text) AS sort_key").
+		Order(sort.order(desc)).
 		Find(&links)
 	return links
 }
+
+// Export calls fn for every link matching the query, reading rows one by one.
+func (repo *LinkRepository) Export(query *LinkQuery, fn func(row *LinkExport) error) error {
+	rows, err := repo.filter(query).
+		Select(`links.id, links.hash, links.url, links.user_id, links.workspace_id, links.domain_id,
+			links.created_at, links.expires_at, links.max_clicks, links.clicks_used,
+			links.title, links.description, links.folder_id, links.redirect_type, links.forward_query,
+			links.utm_source, links.utm_medium, links.utm_campaign, links.utm_term, links.utm_content,
+			(SELECT string_agg(tags.name, ',' ORDER BY tags.name) FROM link_tags
+				JOIN tags ON tags.id = link_tags.tag_id
+				WHERE link_tags.link_id = links.id) AS tag_names`).
+		Order("links.id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row LinkExport
+		if err := repo.DataBase.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if row.TagNames != "" {
+			row.Tags = strings.Split(row.TagNames, ",")
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (repo *LinkRepository) filter(query *LinkQuery) *gorm.DB {
+	tx := repo.DataBase.
+		Table("links").
+		Where("links.deleted_at IS NULL")
+	switch {
+	case query.WorkspaceID != nil:
+		tx = tx.Where("links.workspace_id = ?", *query.WorkspaceID)
+	case !query.All:
+		tx = tx.Where("(links.user_id = ? OR links.workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ?))", query.UserID, query.UserID)
+	}
+	if query.OwnerID != nil {
+		tx = tx.Where("links.user_id = ?", *query.OwnerID)
+	}
+	if query.FolderID != nil {
+		tx = tx.Where("links.folder_id = ?", *query.FolderID)
+	}
+	if query.CreatedFrom != nil {
+		tx = tx.Where("links.created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		tx = tx.Where("links.created_at < ?", *query.CreatedTo)
+	}
+	if query.Search != "" {
+		pattern := "%" + likeEscaper.Replace(query.Search) + "%"
+		tx = tx.Where("(links.url ILIKE ? OR links.hash ILIKE ?)", pattern, pattern)
+	}
+	if query.Tag != "" {
+		tx = tx.Where("links.id IN (SELECT link_tags.link_id FROM link_tags JOIN tags ON tags.id = link_tags.tag_id WHERE tags.name = ?)", query.Tag)
+	}
+	switch query.Status {
+	case LinkStatusActive:
+		tx = tx.Where("NOT "+linkExpired, time.Now())
+	case LinkStatusExpired:
+		tx = tx.Where(linkExpired, time.Now())
+	}
+	return tx
+}
+
+// linkExpired matches links past their expiry date or out of clicks, like Link.IsExpired.
+const linkExpired = "((links.expires_at IS NOT NULL AND links.expires_at <= ?) OR (links.max_clicks IS NOT NULL AND links.clicks_used >= links.max_clicks))"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// linkSort is an order of links by expr, ties go by id. The text value
+// of expr is kept in cursors and compared back as cast.
+type linkSort struct {
+	expr string
+	cast string
+}
+
+func linkSortOf(sort string, desc bool) linkSort {
+	switch sort {
+	case LinkSortCreatedAt:
+		return linkSort{"links.created_at", "timestamptz"}
+	case LinkSortClicks:
+		return linkSort{"(SELECT COALESCE(SUM(stats.clicks), 0) FROM stats WHERE stats.link_id = links.id AND stats.deleted_at IS NULL)", "bigint"}
+	case LinkSortLastVisited:
+		// never visited links go last in both directions
+		never := "'infinity'"
+		if desc {
+			never = "'-infinity'"
+		}
+		return linkSort{"COALESCE((SELECT MAX(clicks.created_at) FROM clicks WHERE clicks.link_id = links.id), " + never + ")", "timestamptz"}
+	}
+	return linkSort{expr: "links.id"}
+}
+
+func (sort linkSort) order(desc bool) string {
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	if sort.cast == "" {
+		return "links.id" + direction
+	}
+	return sort.expr + direction + ", links.id" + direction
+}
+
+// seek selects links following the cursor in the direction.
+func (sort linkSort) seek(tx *gorm.DB, c *cursor.Cursor, desc bool) *gorm.DB {
+	op := " > "
+	if desc {
+		op = " < "
+	}
+	if sort.cast == "" {
+		return tx.Where("links.id"+op+"?", c.ID)
+	}
+	return tx.Where("("+sort.expr+", links.id)"+op+"(CAST(? AS "+sort.cast+"), ?)", c.Key, c.ID)
+}
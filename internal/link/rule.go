@@ -0,0 +1,152 @@
+package link
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"url/short/pkg/useragent"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Rule sends visits that match all of its conditions to its own url. Rules of a link
+// are tried by Position, the first match wins and visits matching none go to Link.Url.
+// Removed rules are soft-deleted, so stats still tell what clicks of a rule id went to.
+type Rule struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	LinkID    uint           `json:"link_id" gorm:"index"`
+	Position  int            `json:"position"`
+	Url       string         `json:"url"`
+
+	// OS and Devices are names of pkg/useragent, Countries ISO 3166 codes and Languages
+	// tags of Accept-Language. An empty condition matches every visit.
+	OS        datatypes.JSONSlice[string] `json:"os"`
+	Devices   datatypes.JSONSlice[string] `json:"devices"`
+	Countries datatypes.JSONSlice[string] `json:"countries"`
+	Languages datatypes.JSONSlice[string] `json:"languages"`
+	// StartsAt and EndsAt bound the time the rule works, EndsAt is exclusive.
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// visitor is what rules match a visit by.
+type visitor struct {
+	os       string
+	device   string
+	country  string
+	language string
+	at       time.Time
+}
+
+func (rule *Rule) matches(v *visitor) bool {
+	if rule.StartsAt != nil && v.at.Before(*rule.StartsAt) {
+		return false
+	}
+	if rule.EndsAt != nil && !v.at.Before(*rule.EndsAt) {
+		return false
+	}
+	return matchAny(rule.OS, v.os, strings.EqualFold) &&
+		matchAny(rule.Devices, v.device, strings.EqualFold) &&
+		matchAny(rule.Countries, v.country, strings.EqualFold) &&
+		matchAny(rule.Languages, v.language, matchLanguage)
+}
+
+func matchAny(values []string, value string, match func(want, got string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, want := range values {
+		if match(want, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLanguage matches a language to the tag of it or of its region, "pt" matches "pt-BR".
+func matchLanguage(want, got string) bool {
+	if len(got) > len(want) && got[len(want)] == '-' {
+		got = got[:len(want)]
+	}
+	return strings.EqualFold(want, got)
+}
+
+// rule returns the first rule of the link matching the visitor, nil when none does.
+func (link *Link) rule(v *visitor) *Rule {
+	for i := range link.Rules {
+		if link.Rules[i].matches(v) {
+			return &link.Rules[i]
+		}
+	}
+	return nil
+}
+
+// needsCountry tells whether any rule of the link looks at the country of the visitor.
+func (link *Link) needsCountry() bool {
+	for _, rule := range link.Rules {
+		if len(rule.Countries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func newVisitor(visit *VisitRequest, at time.Time) *visitor {
+	ua := useragent.Parse(visit.UserAgent)
+	return &visitor{
+		os:       ua.OS,
+		device:   ua.Device,
+		language: preferredLanguage(visit.Language),
+		at:       at,
+	}
+}
+
+// preferredLanguage returns the language tag of an Accept-Language header with the highest
+// weight, the first of equal ones. Only the most preferred language is matched by rules.
+func preferredLanguage(header string) string {
+	preferred, best := "", 0.0
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		tag = strings.TrimSpace(tag)
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if weight, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if tag == "" || tag == "*" || weight <= best {
+			continue
+		}
+		preferred, best = tag, weight
+	}
+	return strings.ToLower(preferred)
+}
+
+func newRules(requests []RuleRequest) []Rule {
+	rules := make([]Rule, len(requests))
+	for i, request := range requests {
+		rules[i] = Rule{
+			Position:  i,
+			Url:       request.Url,
+			OS:        mapStrings(request.OS, strings.TrimSpace),
+			Devices:   mapStrings(request.Devices, strings.ToLower),
+			Countries: mapStrings(request.Countries, strings.ToUpper),
+			Languages: mapStrings(request.Languages, strings.ToLower),
+			StartsAt:  request.StartsAt,
+			EndsAt:    request.EndsAt,
+		}
+	}
+	return rules
+}
+
+func mapStrings(values []string, fn func(string) string) []string {
+	mapped := make([]string, len(values))
+	for i, value := range values {
+		mapped[i] = fn(strings.TrimSpace(value))
+	}
+	return mapped
+}
@@ -0,0 +1,176 @@
+package link
+
+import (
+	"testing"
+	"time"
+	"url/short/pkg/event"
+	"url/short/pkg/req"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type MockLocator struct{}
+
+func (MockLocator) Country(ip string) string {
+	if ip == "5.255.255.5" {
+		return "RU"
+	}
+	return "US"
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"ru-RU,ru;q=0.9,en;q=0.8":   "ru-ru",
+		"en;q=0.5, de-CH, fr;q=0.9": "de-ch",
+		"*, pt-BR;q=0.7":            "pt-br",
+		"en;q=bad, fr;q=0":          "",
+	}
+	for header, want := range cases {
+		if got := preferredLanguage(header); got != want {
+			t.Fatalf("%q: expected %q, got %q", header, want, got)
+		}
+	}
+}
+
+func TestVisitFollowsRules(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.geoIP = MockLocator{}
+	visits := event.Subscribe[event.LinkVisited](service.eventBus, 10)
+	now := time.Now()
+	ended := now.Add(-time.Hour)
+
+	cases := []struct {
+		name   string
+		visit  VisitRequest
+		target string
+		ruleID uint
+	}{
+		{
+			"iphone",
+			VisitRequest{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"},
+			"https://apps.apple.com/app/id1", 11,
+		},
+		{
+			"android phone",
+			VisitRequest{UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/120.0 Mobile Safari/537.36"},
+			"https://play.google.com/store/apps/details?id=dev.go", 12,
+		},
+		{
+			"russian desktop",
+			VisitRequest{UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", IP: "5.255.255.5", Language: "ru-RU,ru;q=0.9"},
+			"https://go.dev/ru", 13,
+		},
+		{
+			"russian speaker abroad",
+			VisitRequest{UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", IP: "8.8.8.8", Language: "ru-RU"},
+			"https://go.dev", 0,
+		},
+	}
+
+	for _, c := range cases {
+		links := sqlmock.NewRows([]string{"id", "url", "hash"}).AddRow(5, "https://go.dev", "promo")
+		rules := sqlmock.NewRows([]string{"id", "link_id", "position", "url", "os", "devices", "countries", "languages", "ends_at"}).
+			AddRow(10, 5, 0, "https://old.go.dev", nil, nil, nil, nil, ended).
+			AddRow(11, 5, 1, "https://apps.apple.com/app/id1", `["iOS"]`, nil, nil, nil, nil).
+			AddRow(12, 5, 2, "https://play.google.com/store/apps/details?id=dev.go", `["Android"]`, `["mobile"]`, nil, nil, nil).
+			AddRow(13, 5, 3, "https://go.dev/ru", nil, nil, `["RU","BY"]`, `["ru"]`, nil)
+		mock.ExpectQuery("SELECT").WillReturnRows(links)
+		mock.ExpectQuery(`FROM "rules" WHERE "rules"."link_id" = \$1 AND "rules"."deleted_at" IS NULL ORDER BY position ASC`).WillReturnRows(rules)
+
+		c.visit.Alias = "promo"
+		_, target, err := service.Visit(&c.visit)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if target != c.target {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.target, target)
+		}
+		if visit := <-visits.Events(); visit.RuleID != c.ruleID {
+			t.Fatalf("%s: expected rule %d, got %d", c.name, c.ruleID, visit.RuleID)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRequestValidation(t *testing.T) {
+	startsAt := time.Now()
+	endsAt := startsAt.Add(-time.Hour)
+	valid := RuleRequest{Url: "https://go.dev", OS: []string{"iOS"}, Devices: []string{"mobile"}, Countries: []string{"de"}, Languages: []string{"pt-BR"}}
+	if err := req.IsValid(LinkRulesRequest{Rules: []RuleRequest{valid}}); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []RuleRequest{
+		{Url: "not a url"},
+		{Url: "https://go.dev", OS: []string{"ios"}},
+		{Url: "https://go.dev", Devices: []string{"phone"}},
+		{Url: "https://go.dev", Countries: []string{"DEU"}},
+		{Url: "https://go.dev", StartsAt: &startsAt, EndsAt: &endsAt},
+	}
+	for _, rule := range invalid {
+		if err := req.IsValid(LinkRulesRequest{Rules: []RuleRequest{rule}}); err == nil {
+			t.Fatalf("%+v: expected error", rule)
+		}
+	}
+
+	rules := newRules([]RuleRequest{valid})
+	if rules[0].Countries[0] != "DE" || rules[0].Languages[0] != "pt-br" || rules[0].Position != 0 {
+		t.Fatalf("Unexpected rule %+v", rules[0])
+	}
+}
+
+func TestSetRulesKeepsIDs(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleColumns := []string{"id", "link_id", "position", "url"}
+	mock.ExpectQuery(`FROM "links"`).WillReturnRows(sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "promo", 1))
+	mock.ExpectQuery(`FROM "rules"`).WillReturnRows(sqlmock.NewRows(ruleColumns).
+		AddRow(11, 5, 0, "https://apps.apple.com").
+		AddRow(12, 5, 1, "https://play.google.com"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "rules" SET "deleted_at"=\$1 WHERE link_id = \$2 AND id NOT IN \(\$3\)`).
+		WithArgs(sqlmock.AnyArg(), 5, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "rules"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(13))
+	mock.ExpectExec(`UPDATE "rules" SET .*"position"=\$\d+,"url"=\$\d+.* WHERE link_id = \$\d+ AND "rules"."deleted_at" IS NULL AND "id" = \$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM "rules"`).WillReturnRows(sqlmock.NewRows(ruleColumns).
+		AddRow(13, 5, 0, "https://www.microsoft.com").
+		AddRow(11, 5, 1, "https://apps.apple.com/app"))
+
+	rules, err := service.SetRules("a@mail.ru", 5, []RuleRequest{
+		{Url: "https://www.microsoft.com", OS: []string{"Windows"}},
+		{ID: 11, Url: "https://apps.apple.com/app", OS: []string{"iOS"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[1].ID != 11 {
+		t.Fatalf("Expected rule 11 to be kept, got %+v", rules)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// ids of rules the link doesn't have are rejected, as are repeated ones
+	for _, ids := range [][]uint{{7}, {11, 11}} {
+		mock.ExpectQuery(`FROM "links"`).WillReturnRows(sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "promo", 1))
+		mock.ExpectQuery(`FROM "rules"`).WillReturnRows(sqlmock.NewRows(ruleColumns).AddRow(11, 5, 0, "https://apps.apple.com"))
+		requests := make([]RuleRequest, len(ids))
+		for i, id := range ids {
+			requests[i] = RuleRequest{ID: id, Url: "https://go.dev"}
+		}
+		if _, err := service.SetRules("a@mail.ru", 5, requests); err == nil || err.Error() != ErrRuleInvalid {
+			t.Fatalf("%v: expected error %q, got %v", ids, ErrRuleInvalid, err)
+		}
+	}
+}
@@ -1,82 +1,753 @@
 package link
 
 import (
-    "errors"
-    "url/short/pkg/event"
+	"errors"
+	"strings"
+	"time"
+	"url/short/internal/domain"
+	"url/short/internal/user"
+	"url/short/pkg/cursor"
+	"url/short/pkg/di"
+	"url/short/pkg/event"
+	"url/short/pkg/geoip"
 
-    "gorm.io/gorm"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+type LinkServiceDeps struct {
+	LinkRepository   *LinkRepository
+	UserRepository   di.IMembershipRepository
+	DomainRepository di.IDomainRepository[*domain.Domain]
+	EventBus         *event.EventBus
+	// Cache of alias lookups, nil disables it.
+	Cache *AliasCache
+	// GeoIP locates visitors for rules by country, without it they never match.
+	GeoIP geoip.Locator
+}
+
 type LinkService struct {
-    repo     *LinkRepository
-    eventBus *event.EventBus
+	repo             *LinkRepository
+	userRepository   di.IMembershipRepository
+	domainRepository di.IDomainRepository[*domain.Domain]
+	eventBus         *event.EventBus
+	cache            *AliasCache
+	geoIP            geoip.Locator
+}
+
+func NewLinkService(deps *LinkServiceDeps) *LinkService {
+	aliasCache := deps.Cache
+	if aliasCache == nil {
+		aliasCache = noCache()
+	}
+	return &LinkService{
+		repo:             deps.LinkRepository,
+		userRepository:   deps.UserRepository,
+		domainRepository: deps.DomainRepository,
+		eventBus:         deps.EventBus,
+		cache:            aliasCache,
+		geoIP:            deps.GeoIP,
+	}
+}
+
+// Create persists the link owned by the user with given email.
+// The alias is used as hash when provided, otherwise a unique hash is generated.
+func (s *LinkService) Create(email string, body *LinkCreateRequest) (*Link, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.create(s.repo, userID, body)
+	if err != nil {
+		return nil, err
+	}
+	// the alias may be remembered as missing
+	s.cache.forget(created.DomainID, created.Hash)
+	s.publishCreated(created)
+	return created, nil
+}
+
+// CreateBulk creates a link for every row. When atomic, rows are created in a single
+// transaction and nothing is created if any row fails, otherwise every row is created on its own.
+// Rows that failed validation before have their error set in results already.
+func (s *LinkService) CreateBulk(email string, rows []*LinkCreateRequest, results []BulkLinkResult, atomic bool) (*BulkLinkResponse, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+
+	createRows := func(repo *LinkRepository) {
+		for i, row := range rows {
+			if results[i].Error != "" {
+				continue
+			}
+			link, err := s.create(repo, userID, row)
+			if err != nil {
+				results[i].Error = err.Error()
+				if atomic {
+					return
+				}
+				continue
+			}
+			results[i].Link = link
+		}
+	}
+
+	response := &BulkLinkResponse{Results: results}
+	if atomic {
+		err = s.repo.Transaction(func(tx *LinkRepository) error {
+			if failed(results) {
+				return errors.New(ErrBulkFailed)
+			}
+			createRows(tx)
+			if failed(results) {
+				return errors.New(ErrBulkFailed)
+			}
+			return nil
+		})
+		if err != nil && err.Error() != ErrBulkFailed {
+			return nil, err
+		}
+		if err != nil {
+			// the transaction is rolled back, no row was created
+			for i := range results {
+				results[i].Link = nil
+				if results[i].Error == "" {
+					results[i].Error = ErrBulkFailed
+				}
+			}
+		}
+	} else {
+		createRows(s.repo)
+	}
+
+	for _, result := range results {
+		if result.Link == nil {
+			response.Failed++
+			continue
+		}
+		response.Created++
+		s.cache.forget(result.Link.DomainID, result.Link.Hash)
+		s.publishCreated(result.Link)
+	}
+	return response, nil
+}
+
+func failed(results []BulkLinkResult) bool {
+	for _, result := range results {
+		if result.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LinkService) publishCreated(link *Link) {
+	s.eventBus.Publish(event.LinkCreated{
+		LinkID: link.ID,
+		UserID: link.UserID,
+		Hash:   link.Hash,
+		Url:    link.Url,
+	})
+}
+
+func (s *LinkService) create(repo *LinkRepository, userID uint, body *LinkCreateRequest) (*Link, error) {
+	if body.WorkspaceID != nil && !s.isMember(*body.WorkspaceID, userID, user.RoleMember) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+
+	link := NewLink(body.Url, userID)
+	link.WorkspaceID = body.WorkspaceID
+	if body.Domain != "" {
+		custom, err := s.linkDomain(body.Domain, userID, body.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		link.DomainID = custom.ID
+		link.WorkspaceID = &custom.WorkspaceID
+	}
+	if body.FolderID != nil {
+		if err := s.checkFolder(repo, *body.FolderID, link.UserID, link.WorkspaceID); err != nil {
+			return nil, err
+		}
+		link.FolderID = body.FolderID
+	}
+	link.Title = body.Title
+	link.Description = body.Description
+	link.ExpiresAt = body.ExpiresAt
+	link.MaxClicks = body.MaxClicks
+	link.RedirectType = body.RedirectType
+	link.ForwardQuery = body.ForwardQuery
+	link.UTM = body.UTM
+	link.Rules = newRules(body.Rules)
+	var err error
+	if link.Password, err = hashPassword(body.Password); err != nil {
+		return nil, err
+	}
+	if link.Tags, err = repo.GetTags(NormalizeTags(body.Tags)); err != nil {
+		return nil, err
+	}
+
+	if body.Alias != "" {
+		if err := s.checkAlias(repo, link.DomainID, body.Alias, 0); err != nil {
+			return nil, err
+		}
+		link.Hash = body.Alias
+	} else {
+		// ensure uniqueness of hash
+		for {
+			existed, _ := repo.GetByHash(link.DomainID, link.Hash)
+			if existed == nil && !IsReservedAlias(link.Hash) {
+				break
+			}
+			link.generateHash()
+		}
+	}
+
+	return repo.Create(link)
+}
+
+// clearedFields are the values LinkUpdateRequest.Clear resets columns to.
+var clearedFields = map[string]any{
+	"expires_at":  nil,
+	"max_clicks":  nil,
+	"password":    "",
+	"title":       "",
+	"description": "",
+}
+
+// Update updates fields of a link owned by the user with given email.
+func (s *LinkService) Update(email string, id uint, body *LinkUpdateRequest) (*Link, error) {
+	existed, err := s.GetOwned(email, id)
+	if err != nil {
+		return nil, err
+	}
+	// a failed update may still have reached the database, so both aliases are forgotten anyway
+	defer s.cache.forget(existed.DomainID, existed.Hash)
+	if body.Hash != "" {
+		defer s.cache.forget(existed.DomainID, body.Hash)
+	}
+
+	fields := map[string]any{"url": body.Url}
+	if body.Hash != "" {
+		fields["hash"] = body.Hash
+	}
+	if body.ExpiresAt != nil {
+		fields["expires_at"] = body.ExpiresAt
+	}
+	if body.MaxClicks != nil {
+		fields["max_clicks"] = body.MaxClicks
+	}
+	if body.Password != "" {
+		password, err := hashPassword(body.Password)
+		if err != nil {
+			return nil, err
+		}
+		fields["password"] = password
+	}
+	if body.Title != "" {
+		fields["title"] = body.Title
+	}
+	if body.Description != "" {
+		fields["description"] = body.Description
+	}
+	if body.FolderID != nil {
+		if *body.FolderID == 0 {
+			fields["folder_id"] = nil
+		} else {
+			fields["folder_id"] = *body.FolderID
+		}
+	}
+	if body.RedirectType != nil {
+		fields["redirect_type"] = *body.RedirectType
+	}
+	if body.ForwardQuery != nil {
+		fields["forward_query"] = *body.ForwardQuery
+	}
+	if body.UTM != nil {
+		fields["utm_source"] = body.UTM.Source
+		fields["utm_medium"] = body.UTM.Medium
+		fields["utm_campaign"] = body.UTM.Campaign
+		fields["utm_term"] = body.UTM.Term
+		fields["utm_content"] = body.UTM.Content
+	}
+	for _, column := range body.Clear {
+		fields[column] = clearedFields[column]
+	}
+
+	var link *Link
+	err = s.repo.Transaction(func(tx *LinkRepository) error {
+		if body.Hash != "" {
+			if err := s.checkAlias(tx, existed.DomainID, body.Hash, id); err != nil {
+				return err
+			}
+		}
+		if body.FolderID != nil && *body.FolderID != 0 {
+			if err := s.checkFolder(tx, *body.FolderID, existed.UserID, existed.WorkspaceID); err != nil {
+				return err
+			}
+		}
+		link, err = tx.Update(id, fields)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.eventBus.Publish(event.LinkUpdated{
+		LinkID:  link.ID,
+		Hash:    link.Hash,
+		OldHash: existed.Hash,
+	})
+	return link, nil
+}
+
+// AddTags labels a link of the user with tags, creating missing ones.
+func (s *LinkService) AddTags(email string, id uint, names []string) (*Link, error) {
+	link, err := s.GetOwned(email, id)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.repo.GetTags(NormalizeTags(names))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddTags(link, tags); err != nil {
+		return nil, err
+	}
+	return link, nil
 }
 
-func NewLinkService(repo *LinkRepository, eventBus *event.EventBus) *LinkService {
-    return &LinkService{repo: repo, eventBus: eventBus}
+func (s *LinkService) RemoveTag(email string, id uint, name string) error {
+	if _, err := s.GetOwned(email, id); err != nil {
+		return err
+	}
+	return s.repo.RemoveTag(id, strings.ToLower(strings.TrimSpace(name)))
 }
 
-// Create generates a unique hash and persists the link.
-func (s *LinkService) Create(url string) (*Link, error) {
-    link := NewLink(url)
+// GetTags returns tags of the links visible to the user, or of a workspace, with link counts.
+func (s *LinkService) GetTags(email string, workspaceID *uint) ([]TagCount, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID != nil && !s.isMember(*workspaceID, userID, user.RoleViewer) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+	return s.repo.GetTagCounts(&LinkQuery{UserID: userID, WorkspaceID: workspaceID}), nil
+}
 
-    // ensure uniqueness of hash
-    for {
-        existed, _ := s.repo.GetByHash(link.Hash)
-        if existed == nil {
-            break
-        }
-        link.generateHash()
-    }
+// CreateFolder creates a personal folder or a folder of a workspace the user may edit links of.
+func (s *LinkService) CreateFolder(email string, body *FolderCreateRequest) (*Folder, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	if body.WorkspaceID != nil && !s.isMember(*body.WorkspaceID, userID, user.RoleMember) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+	return s.repo.CreateFolder(&Folder{
+		Name:        body.Name,
+		UserID:      userID,
+		WorkspaceID: body.WorkspaceID,
+	})
+}
 
-    created, err := s.repo.Create(link)
-    if err != nil {
-        return nil, err
-    }
-    return created, nil
+func (s *LinkService) GetFolders(email string, workspaceID *uint) ([]Folder, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID != nil && !s.isMember(*workspaceID, userID, user.RoleViewer) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+	return s.repo.GetFolders(userID, workspaceID), nil
 }
 
-// Update updates link fields by id.
-func (s *LinkService) Update(id uint, url, hash string) (*Link, error) {
-    // Optional: ensure hash uniqueness if provided
-    if hash != "" {
-        existed, _ := s.repo.GetByHash(hash)
-        if existed != nil && existed.ID != id {
-            return nil, errors.New("hash already in use")
-        }
-    }
+// DeleteFolder deletes a folder, its links are kept outside of any folder.
+func (s *LinkService) DeleteFolder(email string, id uint) error {
+	owner, err := s.user(email)
+	if err != nil {
+		return err
+	}
+	folder, err := s.repo.GetFolder(id)
+	if err != nil {
+		return errors.New(ErrFolderNotFound)
+	}
+	if !owner.HasRole(user.RoleAdmin) {
+		switch {
+		case folder.WorkspaceID != nil && !s.isMember(*folder.WorkspaceID, owner.ID, user.RoleMember):
+			return errors.New(ErrWorkspaceForbidden)
+		case folder.WorkspaceID == nil && folder.UserID != owner.ID:
+			return errors.New(ErrFolderNotFound)
+		}
+	}
+	return s.repo.DeleteFolder(id)
+}
 
-    link, err := s.repo.Update(&Link{Model: gorm.Model{ID: id}, Url: url, Hash: hash})
-    if err != nil {
-        return nil, err
-    }
-    return link, nil
+// checkFolder verifies that links of the owner and workspace may be put into the folder.
+func (s *LinkService) checkFolder(repo *LinkRepository, folderID, userID uint, workspaceID *uint) error {
+	folder, err := repo.GetFolder(folderID)
+	if err != nil {
+		return errors.New(ErrFolderNotFound)
+	}
+	if !folder.Holds(userID, workspaceID) {
+		return errors.New(ErrFolderInvalid)
+	}
+	return nil
 }
 
-// Delete removes link by id.
-func (s *LinkService) Delete(id uint) error {
-    return s.repo.Delete(id)
+// GetRules returns redirect rules of a link the user may see, in the order they are tried.
+func (s *LinkService) GetRules(email string, id uint) ([]Rule, error) {
+	if _, err := s.GetReadable(email, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRules(id), nil
+}
+
+// SetRules replaces redirect rules of a link of the user. Rules given with an id
+// of a current rule keep it, so clicks they sent stay attributed to them.
+func (s *LinkService) SetRules(email string, id uint, requests []RuleRequest) ([]Rule, error) {
+	link, err := s.GetOwned(email, id)
+	if err != nil {
+		return nil, err
+	}
+	existed := make(map[uint]bool)
+	for _, rule := range s.repo.GetRules(id) {
+		existed[rule.ID] = true
+	}
+	rules := newRules(requests)
+	for i, request := range requests {
+		if request.ID == 0 {
+			continue
+		}
+		if !existed[request.ID] {
+			return nil, errors.New(ErrRuleInvalid)
+		}
+		// a rule may be listed once
+		existed[request.ID] = false
+		rules[i].ID = request.ID
+	}
+	if err := s.repo.SetRules(id, rules); err != nil {
+		return nil, err
+	}
+	s.cache.forget(link.DomainID, link.Hash)
+	return s.repo.GetRules(id), nil
+}
+
+// Delete removes a link owned by the user with given email.
+func (s *LinkService) Delete(email string, id uint) error {
+	link, err := s.GetOwned(email, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.cache.forget(link.DomainID, link.Hash)
+	s.eventBus.Publish(event.LinkDeleted{
+		LinkID: link.ID,
+		Hash:   link.Hash,
+	})
+	return nil
 }
 
 func (s *LinkService) GetByID(id uint) (*Link, error) {
-    return s.repo.GetById(id)
+	return s.repo.GetById(id)
 }
 
-// GetAll returns paginated list and total count.
-func (s *LinkService) GetAll(limit, offset int) ([]Link, int64) {
-    links := s.repo.Get(limit, offset)
-    count := s.repo.Count()
-    return links, count
+// GetAll returns a page of the links visible to the user with cursors of
+// the neighbouring pages, and their total count when requested.
+func (s *LinkService) GetAll(email string, query *LinkQuery) (*GetAllLinksResponse, error) {
+	userID, err := s.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	if query.WorkspaceID != nil && !s.isMember(*query.WorkspaceID, userID, user.RoleViewer) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+	query.UserID = userID
+
+	sort := query.SortID()
+	links, next, prev := cursor.Paginate(s.repo.Get(query), query.Page, func(link Link) *cursor.Cursor {
+		return link.cursor(sort)
+	})
+	response := &GetAllLinksResponse{
+		Links:      links,
+		NextCursor: next,
+		PrevCursor: prev,
+	}
+	if query.Page.WithCount {
+		count := s.repo.Count(query)
+		response.Count = &count
+	}
+	return response, nil
 }
 
-// Visit finds link by alias and publishes event.
-func (s *LinkService) Visit(alias string) (*Link, error) {
-    link, err := s.repo.GetByHash(alias)
-    if err != nil {
-        return nil, err
-    }
-    go s.eventBus.Publish(event.Event{Type: event.EventLinkVisited, Data: link.ID})
-    return link, nil
+// PrepareExport scopes the query to links the user may see, admins export links of every user.
+func (s *LinkService) PrepareExport(email string, query *LinkQuery) error {
+	owner, err := s.user(email)
+	if err != nil {
+		return err
+	}
+	query.UserID = owner.ID
+	if owner.HasRole(user.RoleAdmin) {
+		query.All = true
+		return nil
+	}
+	if query.WorkspaceID != nil && !s.isMember(*query.WorkspaceID, owner.ID, user.RoleViewer) {
+		return errors.New(ErrWorkspaceForbidden)
+	}
+	return nil
 }
 
-// no extra helpers
\ No newline at end of file
+// Export streams links of a query prepared by PrepareExport to fn.
+func (s *LinkService) Export(query *LinkQuery, fn func(row *LinkExport) error) error {
+	return s.repo.Export(query, fn)
+}
+
+// Visit finds link by alias, checks that it is still alive and publishes event.
+// It returns the link and the url to redirect to: the url of the first matching rule
+// or of the link, with UTM and forwarded parameters.
+// Protected links are visited only when the request is unlocked.
+func (s *LinkService) Visit(visit *VisitRequest) (*Link, string, error) {
+	link, err := s.resolve(visit.Host, visit.Alias)
+	if err != nil {
+		return nil, "", err
+	}
+	if link.IsProtected() && (visit.Unlocked == nil || !visit.Unlocked(link)) {
+		return nil, "", errors.New(ErrLinkLocked)
+	}
+	if link.MaxClicks != nil {
+		ok, err := s.repo.UseClick(link.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		if !ok {
+			return nil, "", errors.New(ErrLinkExpired)
+		}
+	}
+	now := time.Now()
+	v := newVisitor(visit, now)
+	if s.geoIP != nil && link.needsCountry() {
+		v.country = s.geoIP.Country(visit.IP)
+	}
+	base := link.Url
+	var ruleID uint
+	if rule := link.rule(v); rule != nil {
+		base = rule.Url
+		ruleID = rule.ID
+	}
+	target, params := link.target(base, visit.Query)
+	s.eventBus.Publish(event.LinkVisited{
+		LinkID:    link.ID,
+		Time:      now,
+		IP:        visit.IP,
+		UserAgent: visit.UserAgent,
+		Referrer:  visit.Referrer,
+		Source:    visit.Source,
+		UTM:       params,
+		RuleID:    ruleID,
+	})
+	return link, target, nil
+}
+
+// Find returns the live link of the alias on the host without counting a visit.
+func (s *LinkService) Find(host, alias string) (*Link, error) {
+	return s.resolve(host, alias)
+}
+
+// Host returns the custom domain the link lives on, empty for the service's own host.
+func (s *LinkService) Host(link *Link) (string, error) {
+	if link.DomainID == 0 {
+		return "", nil
+	}
+	custom, err := s.domainRepository.GetById(link.DomainID)
+	if err != nil {
+		return "", err
+	}
+	return custom.Host, nil
+}
+
+// Unlock checks the password of a protected link.
+func (s *LinkService) Unlock(host, alias, password string) (*Link, error) {
+	link, err := s.resolve(host, alias)
+	if err != nil {
+		return nil, err
+	}
+	if !link.IsProtected() {
+		return link, nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.Password), []byte(password)) != nil {
+		return nil, errors.New(ErrWrongPassword)
+	}
+	return link, nil
+}
+
+// resolve finds a link by host and alias that has not expired yet.
+// Hosts other than verified custom domains resolve aliases of the service's own host.
+func (s *LinkService) resolve(host, alias string) (*Link, error) {
+	link, err := s.lookup(s.hostDomain(host), alias)
+	if err != nil {
+		return nil, err
+	}
+	if link.IsExpired(time.Now()) {
+		return nil, errors.New(ErrLinkExpired)
+	}
+	return link, nil
+}
+
+// lookup finds the link of the alias through the cache. Errors of the database
+// are not cached, only aliases that are known to be missing.
+func (s *LinkService) lookup(domainID uint, alias string) (*Link, error) {
+	if link, ok := s.cache.link(domainID, alias); ok {
+		if link == nil {
+			return nil, errors.New(ErrLinkNotFound)
+		}
+		return link, nil
+	}
+	version := s.cache.version()
+	link, err := s.repo.GetByHashWithRules(domainID, alias)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.cache.setLink(version, domainID, alias, nil)
+	}
+	if err != nil {
+		return nil, errors.New(ErrLinkNotFound)
+	}
+	s.cache.setLink(version, domainID, alias, link)
+	return link, nil
+}
+
+// hostDomain returns the verified custom domain of the host, 0 for any other host.
+func (s *LinkService) hostDomain(host string) uint {
+	if host == "" {
+		return 0
+	}
+	host = domain.NormalizeHost(host)
+	if domainID, ok := s.cache.host(host); ok {
+		return domainID
+	}
+	custom, err := s.domainRepository.GetByHost(host)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0
+	}
+	var domainID uint
+	if err == nil && custom.IsVerified() {
+		domainID = custom.ID
+	}
+	s.cache.setHost(host, domainID)
+	return domainID
+}
+
+// linkDomain finds the verified custom domain to create a link on,
+// the user must be a member of its workspace.
+func (s *LinkService) linkDomain(host string, userID uint, workspaceID *uint) (*domain.Domain, error) {
+	custom, err := s.domainRepository.GetByHost(domain.NormalizeHost(host))
+	if err != nil || !custom.IsVerified() {
+		return nil, errors.New(ErrDomainInvalid)
+	}
+	if workspaceID != nil && *workspaceID != custom.WorkspaceID {
+		return nil, errors.New(ErrDomainInvalid)
+	}
+	if !s.isMember(custom.WorkspaceID, userID, user.RoleMember) {
+		return nil, errors.New(ErrWorkspaceForbidden)
+	}
+	return custom, nil
+}
+
+// checkAlias validates the alias and ensures no link other than linkID uses it on the domain.
+func (s *LinkService) checkAlias(repo *LinkRepository, domainID uint, alias string, linkID uint) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+	existed, _ := repo.GetByHash(domainID, alias)
+	if existed != nil && existed.ID != linkID {
+		return errors.New(ErrAliasInUse)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (s *LinkService) userID(email string) (uint, error) {
+	existedUser, err := s.user(email)
+	if err != nil {
+		return 0, err
+	}
+	return existedUser.ID, nil
+}
+
+func (s *LinkService) user(email string) (*user.User, error) {
+	existedUser, _ := s.userRepository.FindByEmail(email)
+	if existedUser == nil {
+		return nil, errors.New(ErrUserNotFound)
+	}
+	return existedUser, nil
+}
+
+// GetOwned loads the link and checks that the user with given email may edit it:
+// owns it or is a member of its workspace. Admins own every link.
+func (s *LinkService) GetOwned(email string, id uint) (*Link, error) {
+	return s.getAccessible(email, id, user.RoleMember)
+}
+
+// GetReadable loads the link and checks that the user with given email may see it and its stats.
+func (s *LinkService) GetReadable(email string, id uint) (*Link, error) {
+	return s.getAccessible(email, id, user.RoleViewer)
+}
+
+func (s *LinkService) getAccessible(email string, id uint, role string) (*Link, error) {
+	owner, err := s.user(email)
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, errors.New(ErrLinkNotFound)
+	}
+	if link.UserID == owner.ID || owner.HasRole(user.RoleAdmin) {
+		return link, nil
+	}
+	if link.WorkspaceID != nil && s.isMember(*link.WorkspaceID, owner.ID, role) {
+		return link, nil
+	}
+	return nil, errors.New(ErrLinkForbidden)
+}
+
+// isMember reports whether the user has at least role in the workspace.
+func (s *LinkService) isMember(workspaceID, userID uint, role string) bool {
+	membership, err := s.userRepository.GetMembership(workspaceID, userID)
+	if err != nil {
+		return false
+	}
+	return user.HasRole(membership.Role, role)
+}
+
+// TakeOver moves a link to the admin with given email.
+func (s *LinkService) TakeOver(email string, id uint) (*Link, error) {
+	admin, err := s.user(email)
+	if err != nil {
+		return nil, err
+	}
+	if !admin.HasRole(user.RoleAdmin) {
+		return nil, errors.New(ErrLinkForbidden)
+	}
+	existed, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, errors.New(ErrLinkNotFound)
+	}
+	link, err := s.repo.Update(id, map[string]any{"user_id": admin.ID})
+	if err != nil {
+		return nil, err
+	}
+	s.cache.forget(existed.DomainID, existed.Hash)
+	return link, nil
+}
@@ -0,0 +1,417 @@
+package link
+
+import (
+	"testing"
+	"time"
+	"url/short/internal/domain"
+	"url/short/internal/user"
+	"url/short/pkg/cursor"
+	"url/short/pkg/db"
+	"url/short/pkg/event"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type MockUserRepository struct {
+}
+
+func (m *MockUserRepository) Create(u *user.User) (*user.User, error) {
+	return u, nil
+}
+
+func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+	if email == "admin@mail.ru" {
+		return &user.User{Model: gorm.Model{ID: 3}, Email: email, Role: user.RoleAdmin}, nil
+	}
+	return &user.User{Model: gorm.Model{ID: 1}, Email: email, Role: user.RoleMember}, nil
+}
+
+func (m *MockUserRepository) GetById(id uint) (*user.User, error) {
+	return &user.User{Model: gorm.Model{ID: id}}, nil
+}
+
+// GetMembership makes user 1 a viewer of workspace 7.
+func (m *MockUserRepository) GetMembership(workspaceID, userID uint) (*user.Membership, error) {
+	if workspaceID != 7 || userID != 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user.Membership{WorkspaceID: workspaceID, UserID: userID, Role: user.RoleViewer}, nil
+}
+
+type MockDomainRepository struct {
+}
+
+// GetByHost knows verified go.team.dev of workspace 7 and unverified new.team.dev.
+func (m *MockDomainRepository) GetByHost(host string) (*domain.Domain, error) {
+	verifiedAt := time.Now()
+	switch host {
+	case "go.team.dev":
+		return &domain.Domain{Model: gorm.Model{ID: 4}, Host: host, WorkspaceID: 7, VerifiedAt: &verifiedAt}, nil
+	case "new.team.dev":
+		return &domain.Domain{Model: gorm.Model{ID: 5}, Host: host, WorkspaceID: 7}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockDomainRepository) GetById(id uint) (*domain.Domain, error) {
+	if id == 4 {
+		return m.GetByHost("go.team.dev")
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func bootstrap() (*LinkService, sqlmock.Sqlmock, error) {
+	database, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: database,
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service := NewLinkService(&LinkServiceDeps{
+		LinkRepository:   NewLinkRepository(&db.DB{DB: gormDB}),
+		UserRepository:   &MockUserRepository{},
+		DomainRepository: &MockDomainRepository{},
+		EventBus:         event.NewEventBus(),
+	})
+	return service, mock, nil
+}
+
+// expectNoRules expects redirect rules of a found alias to be loaded, it has none.
+func expectNoRules(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`FROM "rules"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestDeleteForeignLinkForbidden(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "abcdef", 2)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	err = service.Delete("a@mail.ru", 5)
+	if err == nil || err.Error() != ErrLinkForbidden {
+		t.Fatalf("Expected error %q, got %v", ErrLinkForbidden, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdminDeletesForeignLink(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "abcdef", 2)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "links" SET "deleted_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := service.Delete("admin@mail.ru", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeOverRequiresAdmin(t *testing.T) {
+	service, _, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.TakeOver("a@mail.ru", 5)
+	if err == nil || err.Error() != ErrLinkForbidden {
+		t.Fatalf("Expected error %q, got %v", ErrLinkForbidden, err)
+	}
+}
+
+func TestWorkspaceViewerReadsButCantEdit(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "url", "hash", "user_id", "workspace_id"}).AddRow(5, "https://go.dev", "abcdef", 2, 7)
+	}
+	mock.ExpectQuery("SELECT").WillReturnRows(rows())
+	mock.ExpectQuery("SELECT").WillReturnRows(rows())
+
+	if _, err := service.GetReadable("a@mail.ru", 5); err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.GetOwned("a@mail.ru", 5)
+	if err == nil || err.Error() != ErrLinkForbidden {
+		t.Fatalf("Expected error %q, got %v", ErrLinkForbidden, err)
+	}
+}
+
+func TestCreateInForeignWorkspaceForbidden(t *testing.T) {
+	service, _, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspaceID := uint(8)
+
+	_, err = service.Create("a@mail.ru", &LinkCreateRequest{Url: "https://go.dev", WorkspaceID: &workspaceID})
+	if err == nil || err.Error() != ErrWorkspaceForbidden {
+		t.Fatalf("Expected error %q, got %v", ErrWorkspaceForbidden, err)
+	}
+}
+
+func TestVisitResolvesAliasByHost(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		host     string
+		domainID uint
+	}{
+		{"go.team.dev:443", 4},
+		{"GO.TEAM.DEV", 4},
+		{"new.team.dev", 0},
+		{"localhost:8081", 0},
+	}
+
+	for _, c := range cases {
+		rows := sqlmock.NewRows([]string{"id", "url", "hash", "domain_id"}).AddRow(5, "https://go.dev", "promo", c.domainID)
+		mock.ExpectQuery(`WHERE \(domain_id = \$1 AND hash = \$2\)`).
+			WithArgs(c.domainID, "promo", 1).
+			WillReturnRows(rows)
+		expectNoRules(mock)
+		if _, _, err := service.Visit(&VisitRequest{Host: c.host, Alias: "promo"}); err != nil {
+			t.Fatalf("%s: %v", c.host, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateOnUnverifiedDomain(t *testing.T) {
+	service, _, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.Create("a@mail.ru", &LinkCreateRequest{Url: "https://go.dev", Domain: "new.team.dev"})
+	if err == nil || err.Error() != ErrDomainInvalid {
+		t.Fatalf("Expected error %q, got %v", ErrDomainInvalid, err)
+	}
+}
+
+func TestVisitExpiredLink(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredAt := time.Now().Add(-time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "expires_at"}).AddRow(5, "https://go.dev", "abcdef", expiredAt)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	expectNoRules(mock)
+
+	_, _, err = service.Visit(&VisitRequest{Alias: "abcdef"})
+	if err == nil || err.Error() != ErrLinkExpired {
+		t.Fatalf("Expected error %q, got %v", ErrLinkExpired, err)
+	}
+}
+
+func TestVisitClickLimitReached(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "max_clicks", "clicks_used"}).AddRow(5, "https://go.dev", "abcdef", 3, 2)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	expectNoRules(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "links" SET "clicks_used"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	_, _, err = service.Visit(&VisitRequest{Alias: "abcdef"})
+	if err == nil || err.Error() != ErrLinkExpired {
+		t.Fatalf("Expected error %q, got %v", ErrLinkExpired, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAllSearchFilterSort(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "sort_key"}).AddRow(5, "https://go.dev/50%_off", "promo", "-infinity")
+	mock.ExpectQuery(`\(links\.url ILIKE \$3 OR links\.hash ILIKE \$4\).*tags\.name = \$5.*NOT \(\(links\.expires_at IS NOT NULL.*ORDER BY COALESCE\(\(SELECT MAX\(clicks\.created_at\).*'-infinity'\) DESC, links\.id DESC`).
+		WithArgs(1, 1, `%50\%\_off%`, `%50\%\_off%`, "summer", sqlmock.AnyArg(), 11).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`FROM "link_tags"`).WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"link_id", "tag_id"}).AddRow(5, 2))
+	mock.ExpectQuery(`FROM "tags"`).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "summer"))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "links"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	response, err := service.GetAll("a@mail.ru", &LinkQuery{
+		Search: "50%_off",
+		Tag:    "summer",
+		Status: LinkStatusActive,
+		Sort:   LinkSortLastVisited,
+		Desc:   true,
+		Page:   &cursor.Page{Limit: 10, WithCount: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Links) != 1 || response.Count == nil || *response.Count != 1 {
+		t.Fatalf("Expected 1 link, got %d of %v", len(response.Links), response.Count)
+	}
+	if tags := response.Links[0].Tags; len(tags) != 1 || tags[0].Name != "summer" {
+		t.Fatalf("Expected tag summer, got %+v", tags)
+	}
+	if response.NextCursor != "" || response.PrevCursor != "" {
+		t.Fatalf("Expected a single page, got cursors %q and %q", response.NextCursor, response.PrevCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAllBeforeCursor(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "2024-05-01 10:00:00+00"
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "sort_key"}).
+		AddRow(8, "https://go.dev/8", "eight", "2024-05-02 10:00:00+00").
+		AddRow(9, "https://go.dev/9", "nine", "2024-05-03 10:00:00+00").
+		AddRow(12, "https://go.dev/12", "twelve", "2024-05-04 10:00:00+00")
+	// the page before a cursor of a descending order is read ascending
+	mock.ExpectQuery(`\(links\.created_at, links\.id\) > \(CAST\(\$3 AS timestamptz\), \$4\).*ORDER BY links\.created_at ASC, links\.id ASC`).
+		WithArgs(1, 1, key, 7, 3).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`FROM "link_tags"`).WillReturnRows(sqlmock.NewRows([]string{"link_id", "tag_id"}))
+
+	query := &LinkQuery{Sort: LinkSortCreatedAt, Desc: true}
+	query.Page = &cursor.Page{Limit: 2, Cursor: &cursor.Cursor{Sort: query.SortID(), Key: key, ID: 7, Before: true}}
+	response, err := service.GetAll("a@mail.ru", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Links) != 2 || response.Links[0].ID != 9 || response.Links[1].ID != 8 {
+		t.Fatalf("Expected links 9 and 8, got %+v", response.Links)
+	}
+	next, err := cursor.Decode(response.NextCursor)
+	if err != nil || next.ID != 8 || next.Before {
+		t.Fatalf("Expected next cursor after link 8, got %+v (%v)", next, err)
+	}
+	prev, err := cursor.Decode(response.PrevCursor)
+	if err != nil || prev.ID != 9 || !prev.Before || prev.Key != "2024-05-03 10:00:00+00" {
+		t.Fatalf("Expected prev cursor before link 9, got %+v (%v)", prev, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateInForeignFolder(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	folderID := uint(3)
+	mock.ExpectQuery(`SELECT \* FROM "folders"`).
+		WithArgs(folderID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id"}).AddRow(folderID, "summer", 2))
+
+	_, err = service.Create("a@mail.ru", &LinkCreateRequest{Url: "https://go.dev", FolderID: &folderID})
+	if err == nil || err.Error() != ErrFolderInvalid {
+		t.Fatalf("Expected error %q, got %v", ErrFolderInvalid, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFolderHolds(t *testing.T) {
+	workspaceID, otherID := uint(7), uint(8)
+	personal := &Folder{UserID: 1}
+	shared := &Folder{UserID: 1, WorkspaceID: &workspaceID}
+
+	cases := []struct {
+		folder      *Folder
+		userID      uint
+		workspaceID *uint
+		want        bool
+	}{
+		{personal, 1, nil, true},
+		{personal, 2, nil, false},
+		{personal, 1, &workspaceID, false},
+		{shared, 2, &workspaceID, true},
+		{shared, 1, nil, false},
+		{shared, 1, &otherID, false},
+	}
+	for i, c := range cases {
+		if got := c.folder.Holds(c.userID, c.workspaceID); got != c.want {
+			t.Fatalf("case %d: expected %v, got %v", i, c.want, got)
+		}
+	}
+}
+
+func TestUpdateClearsFields(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "user_id", "max_clicks", "title"}).AddRow(5, "https://go.dev", "abcdef", 1, 10, "Go")
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "links" SET "description"=\$1,"expires_at"=\$2,"max_clicks"=\$3,"title"=\$4,"url"=\$5,"updated_at"=\$6`).
+		WithArgs("Docs", nil, nil, "", "https://go.dev/doc", sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "hash", "user_id", "description"}).AddRow(5, "https://go.dev/doc", "abcdef", 1, "Docs"))
+	mock.ExpectCommit()
+
+	link, err := service.Update("a@mail.ru", 5, &LinkUpdateRequest{
+		Url:         "https://go.dev/doc",
+		Title:       "Golang",
+		Description: "Docs",
+		Clear:       []string{"expires_at", "max_clicks", "title"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.MaxClicks != nil || link.Title != "" || link.Description != "Docs" {
+		t.Fatalf("Unexpected link %+v", link)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateWithTakenAlias(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the alias check reads the link alone, rules are loaded only for redirects
+	mock.ExpectQuery(`SELECT \* FROM "links" WHERE \(domain_id = \$1 AND hash = \$2\)`).WithArgs(0, "promo", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "hash", "user_id"}).AddRow(5, "https://go.dev", "promo", 2))
+
+	_, err = service.Create("a@mail.ru", &LinkCreateRequest{Url: "https://go.dev/doc", Alias: "promo"})
+	if err == nil || err.Error() != ErrAliasInUse {
+		t.Fatalf("Expected error %q, got %v", ErrAliasInUse, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
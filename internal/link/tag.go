@@ -0,0 +1,24 @@
+package link
+
+import (
+	"slices"
+	"strings"
+)
+
+// Tag labels links, a link may have many tags and a tag many links.
+type Tag struct {
+	ID   uint   `json:"id" gorm:"primarykey"`
+	Name string `json:"name" gorm:"uniqueIndex"`
+}
+
+// NormalizeTags lowercases and trims tag names, dropping empty and repeated ones.
+func NormalizeTags(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,37 @@
+package link
+
+import (
+	"net/url"
+	"url/short/pkg/utm"
+)
+
+// target returns the url a visit with the query goes to and the UTM parameters it carries.
+// The link's UTM parameters are set on the base url, the link's or its rule's, and, when
+// the link forwards queries, parameters of the visit are set over them, except the src=qr
+// marker of QR codes.
+func (link *Link) target(base string, query url.Values) (string, utm.Params) {
+	forwarded := url.Values{}
+	if link.ForwardQuery {
+		for name, values := range query {
+			if name == "src" && len(values) > 0 && values[0] == SourceQR {
+				continue
+			}
+			forwarded[name] = values
+		}
+	}
+
+	target, err := url.Parse(base)
+	if err != nil {
+		return base, utm.Params{}
+	}
+	if link.UTM.IsZero() && len(forwarded) == 0 {
+		return base, utm.FromQuery(target.Query())
+	}
+	values := target.Query()
+	link.UTM.Set(values)
+	for name, items := range forwarded {
+		values[name] = items
+	}
+	target.RawQuery = values.Encode()
+	return target.String(), utm.FromQuery(values)
+}
@@ -0,0 +1,68 @@
+package link
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"url/short/configs"
+	"url/short/pkg/event"
+	"url/short/pkg/utm"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLinkTarget(t *testing.T) {
+	campaign := utm.Params{Source: "newsletter", Campaign: "spring"}
+	cases := []struct {
+		name     string
+		link     Link
+		query    string
+		want     string
+		campaign string
+	}{
+		{"plain", Link{Url: "https://go.dev/doc?b=2&a=1"}, "utm_source=x", "https://go.dev/doc?b=2&a=1", ""},
+		{"own utm", Link{Url: "https://go.dev/?utm_campaign=old"}, "", "https://go.dev/?utm_campaign=old", "old"},
+		{"utm", Link{Url: "https://go.dev/doc?utm_campaign=old#top", UTM: campaign}, "", "https://go.dev/doc?utm_campaign=spring&utm_source=newsletter#top", "spring"},
+		{"forward", Link{Url: "https://go.dev/?a=1", ForwardQuery: true}, "a=2&b=3&src=qr", "https://go.dev/?a=2&b=3", ""},
+		{"forward over utm", Link{Url: "https://go.dev/", ForwardQuery: true, UTM: campaign}, "utm_campaign=summer", "https://go.dev/?utm_campaign=summer&utm_source=newsletter", "summer"},
+		{"only marker", Link{Url: "https://go.dev/?b=2&a=1", ForwardQuery: true}, "src=qr", "https://go.dev/?b=2&a=1", ""},
+		{"other src", Link{Url: "https://go.dev/", ForwardQuery: true}, "src=ads", "https://go.dev/?src=ads", ""},
+	}
+
+	for _, c := range cases {
+		query, _ := url.ParseQuery(c.query)
+		target, params := c.link.target(c.link.Url, query)
+		if target != c.want {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.want, target)
+		}
+		if params.Campaign != c.campaign {
+			t.Fatalf("%s: expected campaign %q, got %q", c.name, c.campaign, params.Campaign)
+		}
+	}
+}
+
+func TestGoToForwardsQuery(t *testing.T) {
+	service, mock, err := bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := http.NewServeMux()
+	NewLinkHandler(router, LinkHandlerDeps{LinkService: service, Config: &configs.Config{}})
+	visits := event.Subscribe[event.LinkVisited](service.eventBus, 1)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "hash", "forward_query", "utm_medium"}).
+		AddRow(5, "https://go.dev/", "promo", true, "print")
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	expectNoRules(mock)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/promo?src=qr&utm_source=poster", nil))
+
+	if got := w.Header().Get("Location"); got != "https://go.dev/?utm_medium=print&utm_source=poster" {
+		t.Fatalf("Unexpected location %s", got)
+	}
+	visit := <-visits.Events()
+	if visit.Source != SourceQR || visit.UTM != (utm.Params{Source: "poster", Medium: "print"}) {
+		t.Fatalf("Unexpected visit %+v", visit)
+	}
+}
@@ -0,0 +1,66 @@
+package link
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const unlockCookieTTL = time.Hour
+
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Protected link</title>
+</head>
+<body>
+	<form method="POST" action="/{{.Alias}}">
+		<p>This link is protected. Enter the password to continue.</p>
+		{{if .Error}}<p style="color: #c00">{{.Error}}</p>{{end}}
+		<input type="password" name="password" autofocus required>
+		<button type="submit">Open</button>
+	</form>
+</body>
+</html>
+`))
+
+type unlockPageData struct {
+	Alias string
+	Error string
+}
+
+func unlockCookieName(alias string) string {
+	return "unlock_" + alias
+}
+
+// signUnlock returns a cookie value "<expires>.<signature>" granting access to the link until expires.
+// Changing the password of the link revokes it.
+func signUnlock(secret string, link *Link, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + unlockSignature(secret, link, exp)
+}
+
+// verifyUnlock checks signature and expiration of a value made by signUnlock.
+func verifyUnlock(secret string, link *Link, value string, now time.Time) bool {
+	exp, sig, found := strings.Cut(value, ".")
+	if !found {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(unlockSignature(secret, link, exp)))
+}
+
+func unlockSignature(secret string, link *Link, exp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatUint(uint64(link.ID), 10) + "|" + link.Hash + "|" + link.Password + "|" + exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
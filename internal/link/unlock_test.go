@@ -0,0 +1,33 @@
+package link
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestVerifyUnlock(t *testing.T) {
+	now := time.Now()
+	link := &Link{Model: gorm.Model{ID: 5}, Hash: "docs", Password: "$2a$10$old"}
+	value := signUnlock("secret", link, now.Add(unlockCookieTTL))
+
+	if !verifyUnlock("secret", link, value, now) {
+		t.Fatal("expected valid unlock cookie")
+	}
+	if verifyUnlock("secret", &Link{Model: gorm.Model{ID: 5}, Hash: "other", Password: link.Password}, value, now) {
+		t.Fatal("cookie must not unlock another alias")
+	}
+	if verifyUnlock("secret", &Link{Model: gorm.Model{ID: 6}, Hash: "docs", Password: link.Password}, value, now) {
+		t.Fatal("cookie must not unlock another link with the alias")
+	}
+	if verifyUnlock("secret", &Link{Model: gorm.Model{ID: 5}, Hash: "docs", Password: "$2a$10$new"}, value, now) {
+		t.Fatal("changing the password must revoke the cookie")
+	}
+	if verifyUnlock("another", link, value, now) {
+		t.Fatal("cookie signed with another secret must be rejected")
+	}
+	if verifyUnlock("secret", link, value, now.Add(2*unlockCookieTTL)) {
+		t.Fatal("expired cookie must be rejected")
+	}
+}
@@ -0,0 +1,22 @@
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a login of a user. Its refresh token is stored as a sha256 hash
+// and replaced on every refresh; access tokens carry the session id.
+type Session struct {
+	gorm.Model
+	UserID       uint   `gorm:"index"`
+	RefreshHash  string `gorm:"uniqueIndex"`
+	PreviousHash string `gorm:"index"`
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
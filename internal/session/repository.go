@@ -0,0 +1,92 @@
+package session
+
+import (
+	"time"
+	"url/short/pkg/db"
+)
+
+type SessionRepository struct {
+	database *db.DB
+}
+
+func NewSessionRepository(database *db.DB) *SessionRepository {
+	return &SessionRepository{database: database}
+}
+
+func (repo *SessionRepository) Create(session *Session) (*Session, error) {
+	result := repo.database.DB.Create(session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return session, nil
+}
+
+func (repo *SessionRepository) GetById(id uint) (*Session, error) {
+	var session Session
+	result := repo.database.DB.First(&session, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &session, nil
+}
+
+func (repo *SessionRepository) FindByRefreshHash(hash string) (*Session, error) {
+	var session Session
+	result := repo.database.DB.First(&session, "refresh_hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &session, nil
+}
+
+func (repo *SessionRepository) FindByPreviousHash(hash string) (*Session, error) {
+	var session Session
+	result := repo.database.DB.First(&session, "previous_hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &session, nil
+}
+
+// Rotate replaces the refresh token hash only if it still equals oldHash,
+// so one refresh token can't be exchanged twice by concurrent requests.
+func (repo *SessionRepository) Rotate(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	result := repo.database.DB.Model(&Session{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(map[string]any{
+			"refresh_hash":  newHash,
+			"previous_hash": oldHash,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (repo *SessionRepository) Revoke(id uint) error {
+	return repo.database.DB.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeByUser revokes every session of the user, logging them out everywhere.
+func (repo *SessionRepository) RevokeByUser(userID uint) error {
+	return repo.database.DB.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// IsActive reports whether the session exists and is neither revoked nor expired.
+func (repo *SessionRepository) IsActive(id uint) bool {
+	session, err := repo.GetById(id)
+	if err != nil {
+		return false
+	}
+	return session.IsActive(time.Now())
+}
@@ -1,34 +1,55 @@
 package stat
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 	"url/short/configs"
+	"url/short/internal/user"
+	"url/short/pkg/cursor"
+	"url/short/pkg/di"
+	"url/short/pkg/export"
 	"url/short/pkg/middleware"
+	"url/short/pkg/req"
 	"url/short/pkg/res"
 )
 
 const (
+	GroupByHour  = "hour"
 	GroupByDay   = "day"
+	GroupByWeek  = "week"
 	GroupByMonth = "month"
 )
 
 type StatHandlerDeps struct {
 	StatRepository *StatRepository
+	StatService    *StatService
+	UserRepository di.IUserRepository
 	Config         *configs.Config
+	Auth           middleware.AuthDeps
 }
 
 type StatHandler struct {
 	StatRepository *StatRepository
+	StatService    *StatService
+	UserRepository di.IUserRepository
 }
 
 func NewStatHandler(router *http.ServeMux, deps StatHandlerDeps) {
 
 	handler := &StatHandler{
 		StatRepository: deps.StatRepository,
+		StatService:    deps.StatService,
+		UserRepository: deps.UserRepository,
 	}
 
-	router.Handle("GET /stat", middleware.IsAuthed(handler.GetStat(), deps.Config))
+	router.Handle("GET /stat", middleware.Authed(handler.GetStat(), user.RoleViewer, middleware.ScopeStatsRead, deps.Auth))
+	router.Handle("GET /stat/clicks", middleware.Authed(handler.GetClicks(), user.RoleViewer, middleware.ScopeStatsRead, deps.Auth))
+	router.Handle("GET /stat/export", middleware.Authed(handler.Export(), user.RoleViewer, middleware.ScopeStatsRead, deps.Auth))
+	// pipeline metrics describe the whole service, not the user's links
+	router.Handle("GET /stat/pipeline", middleware.Authed(handler.GetPipeline(), user.RoleAdmin, middleware.ScopeAdmin, deps.Auth))
 
 }
 
@@ -37,21 +58,139 @@ func (h *StatHandler) GetStat() http.HandlerFunc {
 		from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
 		if err != nil {
 			http.Error(w, "Error with parse from param", http.StatusBadRequest)
+			return
 		}
 
 		to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
 		if err != nil {
 			http.Error(w, "Error with parse to param", http.StatusBadRequest)
+			return
 		}
 
 		by := r.URL.Query().Get("by")
 		if by != GroupByDay && by != GroupByMonth {
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
 		}
 
-		stats := h.StatRepository.GetStats(by, from, to)
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		existedUser, err := h.UserRepository.FindByEmail(email)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+		stats := h.StatRepository.GetStats(existedUser.ID, by, from, to, tag)
 
 		res.Json(w, stats, http.StatusOK)
 	}
 
 }
+
+// GetClicks lists raw clicks of links visible to the user, newest first, with the export filters.
+// Pages go by cursor or by limit/offset.
+func (h *StatHandler) GetClicks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := cursor.ParsePage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if page.Cursor != nil && page.Cursor.Sort != clicksSort {
+			http.Error(w, cursor.ErrInvalid, http.StatusBadRequest)
+			return
+		}
+		query, err := parseClickQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.Page = page
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		existedUser, err := h.UserRepository.FindByEmail(email)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		query.UserID = existedUser.ID
+		query.All = existedUser.HasRole(user.RoleAdmin)
+
+		clicks, next, prev := cursor.Paginate(h.StatRepository.GetClicks(query), page, func(click Click) *cursor.Cursor {
+			return &cursor.Cursor{Sort: clicksSort, ID: click.ID}
+		})
+		response := &GetClicksResponse{
+			Clicks:     clicks,
+			NextCursor: next,
+			PrevCursor: prev,
+		}
+		if page.WithCount {
+			count := h.StatRepository.CountClicks(query)
+			response.Count = &count
+		}
+		res.Json(w, response, http.StatusOK)
+	}
+}
+
+// Export streams raw clicks of links visible to the user as CSV or JSON Lines.
+// Admins export clicks of every link. Filters: from/to (YYYY-MM-DD, both inclusive), owner and link_id.
+func (h *StatHandler) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := export.Negotiate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query, err := parseClickQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		existedUser, err := h.UserRepository.FindByEmail(email)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		query.UserID = existedUser.ID
+		query.All = existedUser.HasRole(user.RoleAdmin)
+
+		writer, err := export.NewWriter(w, format, "clicks", clickExportHeader)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Finish(h.StatRepository.ExportClicks(query, func(click *Click) error {
+			return writer.Write(click)
+		}))
+	}
+}
+
+func parseClickQuery(r *http.Request) (*ClickQuery, error) {
+	params := r.URL.Query()
+	query := &ClickQuery{Tag: strings.ToLower(strings.TrimSpace(params.Get("tag")))}
+
+	for name, target := range map[string]**uint{"owner": &query.OwnerID, "link_id": &query.LinkID} {
+		if value := params.Get(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, errors.New("Error with parsing " + name)
+			}
+			n := uint(id)
+			*target = &n
+		}
+	}
+	var err error
+	if query.From, query.To, err = req.DateRange(params); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func (h *StatHandler) GetPipeline() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res.Json(w, h.StatService.Metrics(), http.StatusOK)
+	}
+}
@@ -1,13 +1,70 @@
 package stat
 
 import (
+	"strconv"
+	"time"
+	"url/short/pkg/utm"
+
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type Stat struct {
 	gorm.Model
-	LinkId uint           `json:"link_id"`
+	LinkId uint           `json:"link_id" gorm:"uniqueIndex:idx_stats_link_date"`
 	Clicks uint           `json:"clicks"`
-	Date   datatypes.Date `json:"date"`
+	Date   datatypes.Date `json:"date" gorm:"uniqueIndex:idx_stats_link_date"`
+}
+
+// Click is a single raw visit of a link.
+type Click struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	LinkId    uint      `json:"link_id" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Browser   string    `json:"browser"`
+	OS        string    `json:"os"`
+	Device    string    `json:"device"`
+	Country   string    `json:"country"`
+	Source    string    `json:"source"`
+	// UTM are the campaign parameters of the url the visitor was sent to.
+	UTM utm.Params `json:"utm" gorm:"embedded;embeddedPrefix:utm_"`
+	// RuleID is the redirect rule that sent the visitor, nil when it went to the link's url.
+	RuleID *uint `json:"rule_id" gorm:"index"`
+}
+
+var clickExportHeader = []string{
+	"id", "link_id", "created_at", "referrer", "user_agent", "ip", "browser", "os", "device", "country", "source",
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "rule_id",
+}
+
+func (click *Click) CSV() []string {
+	return []string{
+		strconv.FormatUint(uint64(click.ID), 10),
+		strconv.FormatUint(uint64(click.LinkId), 10),
+		click.CreatedAt.UTC().Format(time.RFC3339),
+		click.Referrer,
+		click.UserAgent,
+		click.IP,
+		click.Browser,
+		click.OS,
+		click.Device,
+		click.Country,
+		click.Source,
+		click.UTM.Source,
+		click.UTM.Medium,
+		click.UTM.Campaign,
+		click.UTM.Term,
+		click.UTM.Content,
+		formatUint(click.RuleID),
+	}
+}
+
+func formatUint(n *uint) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*n), 10)
 }
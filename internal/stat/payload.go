@@ -1,6 +1,88 @@
 package stat
 
+import (
+	"time"
+	"url/short/pkg/cursor"
+)
+
 type GetStatResponse struct {
 	Period string `json:"period"`
 	Sum    int    `json:"sum"`
 }
+
+type ClickMetricsResponse struct {
+	Tracked uint64 `json:"tracked"`
+	Dropped uint64 `json:"dropped"`
+	Written uint64 `json:"written"`
+	Failed  uint64 `json:"failed"`
+	Batches uint64 `json:"batches"`
+	Queued  int    `json:"queued"`
+	Buffer  int    `json:"buffer"`
+}
+
+// LinkStatsQuery filters clicks of a single link; To is exclusive.
+type LinkStatsQuery struct {
+	From time.Time
+	To   time.Time
+	By   string
+	Top  int
+}
+
+// ClickQuery filters raw clicks of links visible to UserID, or of every link when All is set.
+// To is exclusive.
+type ClickQuery struct {
+	UserID  uint
+	All     bool
+	OwnerID *uint
+	LinkID  *uint
+	Tag     string
+	From    *time.Time
+	To      *time.Time
+	// Page is nil for exports.
+	Page *cursor.Page
+}
+
+// clicksSort names the only order of the click listing in its cursors.
+const clicksSort = "id desc"
+
+type GetClicksResponse struct {
+	Clicks []Click `json:"clicks"`
+	// Count is set only when requested with count=true.
+	Count      *int64 `json:"count,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type Breakdown struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// RuleBreakdown counts visits a redirect rule sent, RuleID is nil for visits that went to the link's url.
+// Url and Deleted describe the rule, which may have been removed since.
+type RuleBreakdown struct {
+	RuleID  *uint  `json:"rule_id"`
+	Url     string `json:"url"`
+	Deleted bool   `json:"deleted"`
+	Count   int64  `json:"count"`
+}
+
+type LinkStatsResponse struct {
+	LinkId    uint              `json:"link_id"`
+	Total     int64             `json:"total"`
+	Unique    int64             `json:"unique"`
+	Series    []GetStatResponse `json:"series"`
+	Referrers []Breakdown       `json:"referrers"`
+	Countries []Breakdown       `json:"countries"`
+	Browsers  []Breakdown       `json:"browsers"`
+	Devices   []Breakdown       `json:"devices"`
+	Sources   []Breakdown       `json:"sources"`
+
+	UtmSources   []Breakdown `json:"utm_sources"`
+	UtmMediums   []Breakdown `json:"utm_mediums"`
+	UtmCampaigns []Breakdown `json:"utm_campaigns"`
+	UtmTerms     []Breakdown `json:"utm_terms"`
+	UtmContents  []Breakdown `json:"utm_contents"`
+	// Rules counts visits by the redirect rule that sent them.
+	Rules []RuleBreakdown `json:"rules"`
+}
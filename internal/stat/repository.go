@@ -2,6 +2,9 @@ package stat
 
 import (
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
 	"time"
 	"url/short/pkg/db"
 )
@@ -17,24 +20,201 @@ func NewStatRepository(DB *db.DB) *StatRepository {
 }
 
 func (repo StatRepository) AddClick(linkId uint) {
-	var stat Stat
-	currentDate := datatypes.Date(time.Now())
-	repo.DB.Find(&stat, "link_id = ? AND date = ?", linkId, currentDate)
-	if stat.ID == 0 {
+	incrementClicks(repo.DB.DB, []Stat{{
+		LinkId: linkId,
+		Clicks: 1,
+		Date:   datatypes.Date(time.Now()),
+	}})
+}
+
+// AddClicks stores a batch of raw clicks and adds them to the daily counters in one transaction.
+func (repo StatRepository) AddClicks(clicks []*Click) error {
+	type key struct {
+		linkId uint
+		date   string
+	}
+	counters := make(map[key]*Stat)
+	for _, click := range clicks {
+		k := key{click.LinkId, click.CreatedAt.Format("2006-01-02")}
+		if counters[k] == nil {
+			counters[k] = &Stat{LinkId: click.LinkId, Date: datatypes.Date(click.CreatedAt)}
+		}
+		counters[k].Clicks++
+	}
+
+	stats := make([]Stat, 0, len(counters))
+	for _, stat := range counters {
+		stats = append(stats, *stat)
+	}
+	// lock rows in the same order in every transaction to avoid deadlocks
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].LinkId != stats[j].LinkId {
+			return stats[i].LinkId < stats[j].LinkId
+		}
+		return time.Time(stats[i].Date).Before(time.Time(stats[j].Date))
+	})
+
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(clicks, 500).Error; err != nil {
+			return err
+		}
+		return incrementClicks(tx, stats)
+	})
+}
+
+// incrementClicks adds the counters to the daily stats with a single
+// INSERT ... ON CONFLICT (link_id, date) DO UPDATE statement.
+func incrementClicks(tx *gorm.DB, stats []Stat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "link_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"clicks":     gorm.Expr("stats.clicks + excluded.clicks"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&stats).Error
+}
+
+var seriesSelects = map[string]string{
+	GroupByHour:  `to_char(date_trunc('hour', created_at), 'YYYY-MM-DD HH24:00') as period, count(*) as sum`,
+	GroupByDay:   `to_char(created_at, 'YYYY-MM-DD') as period, count(*) as sum`,
+	GroupByWeek:  `to_char(date_trunc('week', created_at), 'YYYY-MM-DD') as period, count(*) as sum`,
+	GroupByMonth: `to_char(created_at, 'YYYY-MM') as period, count(*) as sum`,
+}
+
+// GetLinkStats aggregates raw clicks of one link into totals, time series and top-N breakdowns.
+func (repo StatRepository) GetLinkStats(linkId uint, query *LinkStatsQuery) *LinkStatsResponse {
+	stats := &LinkStatsResponse{
+		LinkId: linkId,
+	}
+
+	clicks := func() *gorm.DB {
+		return repo.DB.Table("clicks").
+			Where("link_id = ? AND created_at >= ? AND created_at < ?", linkId, query.From, query.To)
+	}
+
+	clicks().Count(&stats.Total)
+	clicks().Select("count(DISTINCT (ip, user_agent))").Scan(&stats.Unique)
 
-		repo.DB.Create(&Stat{
-			LinkId: linkId,
-			Clicks: 1,
-			Date:   currentDate,
-		})
-	} else {
-		stat.Clicks += 1
-		repo.DB.Save(&stat)
+	clicks().
+		Select(seriesSelects[query.By]).
+		Group("period").
+		Order("period asc").
+		Scan(&stats.Series)
+
+	breakdowns := map[string]*[]Breakdown{
+		"referrer": &stats.Referrers,
+		"country":  &stats.Countries,
+		"browser":  &stats.Browsers,
+		"device":   &stats.Devices,
+		"source":   &stats.Sources,
+
+		"utm_source":   &stats.UtmSources,
+		"utm_medium":   &stats.UtmMediums,
+		"utm_campaign": &stats.UtmCampaigns,
+		"utm_term":     &stats.UtmTerms,
+		"utm_content":  &stats.UtmContents,
 	}
+	for column, target := range breakdowns {
+		clicks().
+			Select(column + " as value, count(*) as count").
+			Group(column).
+			Order("count desc").
+			Limit(query.Top).
+			Scan(target)
+	}
+
+	// rules are soft-deleted, so removed ones are still told apart
+	repo.DB.Table("clicks").
+		Select("clicks.rule_id, COALESCE(rules.url, '') AS url, rules.deleted_at IS NOT NULL AS deleted, count(*) AS count").
+		Joins("LEFT JOIN rules ON rules.id = clicks.rule_id").
+		Where("clicks.link_id = ? AND clicks.created_at >= ? AND clicks.created_at < ?", linkId, query.From, query.To).
+		Group("clicks.rule_id, rules.url, rules.deleted_at").
+		Order("count desc").
+		Limit(query.Top).
+		Scan(&stats.Rules)
 
+	return stats
 }
 
-func (repo StatRepository) GetStats(by string, from, to time.Time) []GetStatResponse {
+// visibleLinks selects ids of the user's links and links of their workspaces.
+const visibleLinks = "SELECT id FROM links WHERE deleted_at IS NULL AND (user_id = ? OR workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ?))"
+
+// taggedLinks selects ids of links labeled with a tag.
+const taggedLinks = "SELECT link_tags.link_id FROM link_tags JOIN tags ON tags.id = link_tags.tag_id WHERE tags.name = ?"
+
+// ExportClicks calls fn for every click matching the query, reading rows one by one.
+func (repo StatRepository) ExportClicks(query *ClickQuery, fn func(click *Click) error) error {
+	rows, err := repo.clicks(query).Order("id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var click Click
+		if err := repo.DB.ScanRows(rows, &click); err != nil {
+			return err
+		}
+		if err := fn(&click); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetClicks returns a page of clicks, newest first. With a cursor it fetches up to
+// Limit+1 clicks after the cursor, or before it in reverse order, for cursor.Paginate.
+func (repo StatRepository) GetClicks(query *ClickQuery) []Click {
+	var clicks []Click
+
+	page := query.Page
+	tx := repo.clicks(query).Limit(page.Limit + 1)
+	switch {
+	case page.Cursor == nil:
+		tx = tx.Order("id DESC").Offset(page.Offset)
+	case page.Cursor.Before:
+		tx = tx.Where("id > ?", page.Cursor.ID).Order("id ASC")
+	default:
+		tx = tx.Where("id < ?", page.Cursor.ID).Order("id DESC")
+	}
+	tx.Find(&clicks)
+	return clicks
+}
+
+func (repo StatRepository) CountClicks(query *ClickQuery) int64 {
+	var count int64
+	repo.clicks(query).Count(&count)
+	return count
+}
+
+func (repo StatRepository) clicks(query *ClickQuery) *gorm.DB {
+	tx := repo.DB.Model(&Click{})
+	if !query.All {
+		tx = tx.Where("link_id IN ("+visibleLinks+")", query.UserID, query.UserID)
+	}
+	if query.OwnerID != nil {
+		tx = tx.Where("link_id IN (SELECT id FROM links WHERE user_id = ?)", *query.OwnerID)
+	}
+	if query.LinkID != nil {
+		tx = tx.Where("link_id = ?", *query.LinkID)
+	}
+	if query.Tag != "" {
+		tx = tx.Where("link_id IN ("+taggedLinks+")", query.Tag)
+	}
+	if query.From != nil {
+		tx = tx.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		tx = tx.Where("created_at < ?", *query.To)
+	}
+	return tx
+}
+
+// GetStats sums clicks of the links visible to the user: their own links and links of their workspaces.
+func (repo StatRepository) GetStats(userID uint, by string, from, to time.Time, tag string) []GetStatResponse {
 	var stats []GetStatResponse
 	var selectQuery string
 
@@ -45,10 +225,14 @@ func (repo StatRepository) GetStats(by string, from, to time.Time) []GetStatResp
 		selectQuery = "to_char(date, 'YYYY-MM') as period, sum(clicks) as sum"
 	}
 
-	repo.DB.Table("stats").
+	tx := repo.DB.Table("stats").
 		Select(selectQuery).
 		Where("date BETWEEN ? AND ?", from, to).
-		Group("period").
+		Where("link_id IN ("+visibleLinks+")", userID, userID)
+	if tag != "" {
+		tx = tx.Where("link_id IN ("+taggedLinks+")", tag)
+	}
+	tx.Group("period").
 		Order("period desc").
 		Scan(&stats)
 
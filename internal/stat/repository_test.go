@@ -0,0 +1,88 @@
+package stat
+
+import (
+	"testing"
+	"time"
+	"url/short/pkg/db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAddClicksUpsertsCountersInOneStatement(t *testing.T) {
+	database, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: database,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewStatRepository(&db.DB{DB: gormDB})
+
+	now := time.Now()
+	clicks := []*Click{
+		{LinkId: 1, CreatedAt: now},
+		{LinkId: 1, CreatedAt: now},
+		{LinkId: 2, CreatedAt: now},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "clicks"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "stats" .+ ON CONFLICT \("link_id","date"\) DO UPDATE SET "clicks"=stats.clicks \+ excluded.clicks`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(2), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(2), uint(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	if err := repo.AddClicks(clicks); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinkStatsDescribeRules(t *testing.T) {
+	database, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: database,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewStatRepository(&db.DB{DB: gormDB})
+
+	// breakdowns are queried in no particular order
+	mock.MatchExpectationsInOrder(false)
+	ruleID := uint(11)
+	mock.ExpectQuery(`SELECT clicks.rule_id, .+ FROM "clicks" LEFT JOIN rules ON rules.id = clicks.rule_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"rule_id", "url", "deleted", "count"}).
+			AddRow(ruleID, "https://apps.apple.com", true, 3).
+			AddRow(nil, "", false, 2))
+	for i := 0; i < 13; i++ {
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	stats := repo.GetLinkStats(5, &LinkStatsQuery{From: today, To: today.AddDate(0, 0, 1), By: GroupByDay, Top: 10})
+	if len(stats.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %+v", stats.Rules)
+	}
+	if removed := stats.Rules[0]; removed.RuleID == nil || *removed.RuleID != ruleID || removed.Url != "https://apps.apple.com" || !removed.Deleted {
+		t.Fatalf("Expected the removed rule %d described, got %+v", ruleID, removed)
+	}
+	if own := stats.Rules[1]; own.RuleID != nil || own.Count != 2 {
+		t.Fatalf("Expected visits of the link's url, got %+v", own)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
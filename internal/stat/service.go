@@ -1,36 +1,167 @@
 package stat
 
 import (
-	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url/short/configs"
 	"url/short/pkg/event"
+	"url/short/pkg/geoip"
+	"url/short/pkg/useragent"
 )
 
+type IClickRepository interface {
+	AddClicks(clicks []*Click) error
+}
+
 type StatServiceDeps struct {
 	EventBus       *event.EventBus
-	StatRepository *StatRepository
+	StatRepository IClickRepository
+	GeoIP          geoip.Locator
+	Config         *configs.Config
 }
 
+// StatService is a bounded click pipeline: it subscribes to link visits with
+// a buffer of Click.BufferSize, a pool of workers enriches the visits and
+// writes them to the database in batches. A failed batch is retried with
+// a growing backoff before its clicks are counted as failed.
 type StatService struct {
-	EventBus       *event.EventBus
-	StatRepository *StatRepository
+	StatRepository IClickRepository
+	GeoIP          geoip.Locator
+
+	visits        *event.Subscription[event.LinkVisited]
+	workers       int
+	batchSize     int
+	flushInterval time.Duration
+	flushRetries  int
+	retryBackoff  time.Duration
+
+	wg      sync.WaitGroup
+	metrics clickMetrics
+}
+
+type clickMetrics struct {
+	written atomic.Uint64
+	failed  atomic.Uint64
+	batches atomic.Uint64
 }
 
 func NewStatService(deps *StatServiceDeps) *StatService {
+	conf := deps.Config.Click
 	return &StatService{
-		EventBus:       deps.EventBus,
 		StatRepository: deps.StatRepository,
+		GeoIP:          deps.GeoIP,
+		visits:         event.Subscribe[event.LinkVisited](deps.EventBus, conf.BufferSize),
+		workers:        conf.Workers,
+		batchSize:      conf.BatchSize,
+		flushInterval:  conf.FlushInterval,
+		flushRetries:   conf.FlushRetries,
+		retryBackoff:   conf.RetryBackoff,
+	}
+}
+
+// Start launches the worker pool.
+func (s *StatService) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+}
+
+// Close unsubscribes from visits and waits until every buffered visit is written.
+func (s *StatService) Close() {
+	s.visits.Unsubscribe()
+	s.wg.Wait()
+}
+
+func (s *StatService) Metrics() ClickMetricsResponse {
+	return ClickMetricsResponse{
+		Tracked: s.visits.Delivered(),
+		Dropped: s.visits.Dropped(),
+		Written: s.metrics.written.Load(),
+		Failed:  s.metrics.failed.Load(),
+		Batches: s.metrics.batches.Load(),
+		Queued:  s.visits.Len(),
+		Buffer:  s.visits.Cap(),
 	}
 }
 
-func (s *StatService) AddClick() {
+func (s *StatService) work() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Click, 0, s.batchSize)
 	for {
 		select {
-		case msg := <-s.EventBus.Subscribe():
-			if msg.Type == event.EventLinkVisited {
-				s.StatRepository.AddClick(msg.Data.(uint))
-				fmt.Println("Event link visited")
+		case visit, ok := <-s.visits.Events():
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, s.NewClick(visit))
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = make([]*Click, 0, s.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]*Click, 0, s.batchSize)
 			}
+		}
+	}
+}
 
+func (s *StatService) flush(batch []*Click) {
+	if len(batch) == 0 {
+		return
+	}
+	s.metrics.batches.Add(1)
+	backoff := s.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.StatRepository.AddClicks(batch)
+		if err == nil {
+			s.metrics.written.Add(uint64(len(batch)))
+			return
 		}
+		if attempt >= s.flushRetries {
+			s.metrics.failed.Add(uint64(len(batch)))
+			log.Printf("Error saving clicks, %d clicks lost: %v", len(batch), err)
+			return
+		}
+		log.Printf("Error saving clicks, retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		// the failed transaction was rolled back, ids it assigned don't exist
+		for _, click := range batch {
+			click.ID = 0
+		}
+	}
+}
+
+// NewClick enriches a visit with parsed user agent and country.
+func (s *StatService) NewClick(visit event.LinkVisited) *Click {
+	ua := useragent.Parse(visit.UserAgent)
+	click := &Click{
+		LinkId:    visit.LinkID,
+		CreatedAt: visit.Time,
+		Referrer:  visit.Referrer,
+		UserAgent: visit.UserAgent,
+		IP:        visit.IP,
+		Browser:   ua.Browser,
+		OS:        ua.OS,
+		Device:    ua.Device,
+		Source:    visit.Source,
+		UTM:       visit.UTM,
+	}
+	if visit.RuleID != 0 {
+		click.RuleID = &visit.RuleID
+	}
+	if s.GeoIP != nil {
+		click.Country = s.GeoIP.Country(visit.IP)
 	}
+	return click
 }
@@ -0,0 +1,106 @@
+package stat
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"url/short/configs"
+	"url/short/pkg/event"
+)
+
+type MockClickRepository struct {
+	mu      sync.Mutex
+	clicks  int
+	batches []int
+	// failures is the number of calls that fail before writes succeed.
+	failures int
+}
+
+func (m *MockClickRepository) AddClicks(clicks []*Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection reset")
+	}
+	m.clicks += len(clicks)
+	m.batches = append(m.batches, len(clicks))
+	return nil
+}
+
+func newTestService(bus *event.EventBus, repo IClickRepository, buffer int) *StatService {
+	return NewStatService(&StatServiceDeps{
+		EventBus:       bus,
+		StatRepository: repo,
+		Config: &configs.Config{
+			Click: configs.Clickconfig{
+				BufferSize:    buffer,
+				Workers:       4,
+				BatchSize:     50,
+				FlushInterval: time.Hour,
+			},
+		},
+	})
+}
+
+func TestCloseDrainsQueuedClicks(t *testing.T) {
+	const visits = 1000
+	bus := event.NewEventBus()
+	repo := &MockClickRepository{}
+	service := newTestService(bus, repo, visits)
+	service.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < visits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(event.LinkVisited{LinkID: 1, Time: time.Now()})
+		}()
+	}
+	wg.Wait()
+	service.Close()
+
+	if repo.clicks != visits {
+		t.Fatalf("Expected %d clicks written, got %d", visits, repo.clicks)
+	}
+	for _, size := range repo.batches {
+		if size > 50 {
+			t.Fatalf("Expected batches of at most 50 clicks, got %d", size)
+		}
+	}
+	if metrics := service.Metrics(); metrics.Written != visits || metrics.Dropped != 0 {
+		t.Fatalf("Unexpected metrics %+v", metrics)
+	}
+}
+
+func TestVisitsDroppedWhenBufferIsFull(t *testing.T) {
+	bus := event.NewEventBus()
+	service := newTestService(bus, &MockClickRepository{}, 2)
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(event.LinkVisited{LinkID: 1})
+	}
+	if dropped := service.Metrics().Dropped; dropped != 1 {
+		t.Fatalf("Expected 1 dropped click, got %d", dropped)
+	}
+}
+
+func TestFailedBatchIsRetried(t *testing.T) {
+	repo := &MockClickRepository{failures: 2}
+	service := newTestService(event.NewEventBus(), repo, 10)
+	service.flushRetries = 2
+	service.retryBackoff = time.Millisecond
+
+	service.flush([]*Click{{LinkId: 1}, {LinkId: 2}})
+	if metrics := service.Metrics(); repo.clicks != 2 || metrics.Written != 2 || metrics.Failed != 0 {
+		t.Fatalf("Expected the batch written on the last retry, got %d clicks, %+v", repo.clicks, metrics)
+	}
+
+	repo.failures = 3
+	service.flush([]*Click{{LinkId: 1}})
+	if metrics := service.Metrics(); repo.clicks != 2 || metrics.Failed != 1 {
+		t.Fatalf("Expected the click lost after retries, got %+v", metrics)
+	}
+}
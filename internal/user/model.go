@@ -2,10 +2,37 @@ package user
 
 import "gorm.io/gorm"
 
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+}
+
 type User struct {
 	gorm.Model
-	Email string `gorm:"index"`
-	Password string
-	Name string
+	Email    string `json:"email" gorm:"index"`
+	Password string `json:"-"`
+	Name     string `json:"name"`
+	Role     string `json:"role" gorm:"default:member"`
+	Disabled bool   `json:"disabled"`
+}
+
+func IsRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role grants at least the rights of required.
+func HasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required] && IsRole(role)
+}
 
-}
\ No newline at end of file
+func (u *User) HasRole(required string) bool {
+	return !u.Disabled && HasRole(u.Role, required)
+}
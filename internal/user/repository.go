@@ -1,6 +1,11 @@
 package user
 
-import "url/short/pkg/db"
+import (
+	"url/short/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type UserRepository struct {
 	database *db.DB
@@ -19,6 +24,42 @@ func (repo *UserRepository) Create(user *User) (*User, error) {
 	return user, nil
 }
 
+func (repo *UserRepository) GetById(id uint) (*User, error) {
+	var user User
+	result := repo.database.DB.First(&user, id)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (repo *UserRepository) Update(user *User) (*User, error) {
+	result := repo.database.DB.Save(user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return user, nil
+}
+
+func (repo *UserRepository) Count() int64 {
+	var count int64
+	repo.database.DB.Model(&User{}).Count(&count)
+	return count
+}
+
+func (repo *UserRepository) Get(limit, offset int) []User {
+	var users []User
+	repo.database.DB.
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users)
+	return users
+}
+
 func (repo *UserRepository) FindByEmail(email string) (*User, error) {
 	var user User
 	result := repo.database.DB.First(&user, "email = ?", email)
@@ -29,3 +70,82 @@ func (repo *UserRepository) FindByEmail(email string) (*User, error) {
 
 	return &user, nil
 }
+
+// CreateWorkspace creates the workspace with the owner as its admin.
+func (repo *UserRepository) CreateWorkspace(workspace *Workspace, ownerID uint) (*Workspace, error) {
+	err := repo.database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Membership{
+			WorkspaceID: workspace.ID,
+			UserID:      ownerID,
+			Role:        RoleAdmin,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+func (repo *UserRepository) GetWorkspace(id uint) (*Workspace, error) {
+	var workspace Workspace
+	result := repo.database.DB.First(&workspace, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &workspace, nil
+}
+
+// GetWorkspaces returns the workspaces the user is a member of.
+func (repo *UserRepository) GetWorkspaces(userID uint) []Workspace {
+	var workspaces []Workspace
+	repo.database.DB.
+		Joins("JOIN memberships ON memberships.workspace_id = workspaces.id").
+		Where("memberships.user_id = ?", userID).
+		Order("workspaces.id ASC").
+		Find(&workspaces)
+	return workspaces
+}
+
+func (repo *UserRepository) GetMembership(workspaceID, userID uint) (*Membership, error) {
+	var membership Membership
+	result := repo.database.DB.First(&membership, "workspace_id = ? AND user_id = ?", workspaceID, userID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &membership, nil
+}
+
+func (repo *UserRepository) GetMembers(workspaceID uint) []Membership {
+	var members []Membership
+	repo.database.DB.
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at ASC").
+		Find(&members)
+	return members
+}
+
+// SaveMembership adds the user to the workspace or changes their role.
+func (repo *UserRepository) SaveMembership(membership *Membership) (*Membership, error) {
+	result := repo.database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(membership)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return membership, nil
+}
+
+func (repo *UserRepository) DeleteMembership(workspaceID, userID uint) error {
+	return repo.database.DB.
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&Membership{}).Error
+}
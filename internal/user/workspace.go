@@ -0,0 +1,24 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Workspace shares links and their stats between its members.
+type Workspace struct {
+	gorm.Model
+	Name    string       `json:"name"`
+	Members []Membership `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// Membership gives the user a role in the workspace: viewers see links and stats,
+// members also create and edit links, admins also manage members.
+type Membership struct {
+	WorkspaceID uint      `json:"workspace_id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"primaryKey;index"`
+	User        *User     `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
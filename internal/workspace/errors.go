@@ -0,0 +1,8 @@
+package workspace
+
+const (
+	ErrUserNotFound       = "user not found"
+	ErrMemberNotFound     = "member not found"
+	ErrWorkspaceForbidden = "not enough rights in the workspace"
+	ErrLastAdmin          = "workspace must keep at least one admin"
+)
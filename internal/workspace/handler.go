@@ -0,0 +1,138 @@
+package workspace
+
+import (
+	"net/http"
+	"strconv"
+	"url/short/internal/user"
+	"url/short/pkg/middleware"
+	"url/short/pkg/req"
+	"url/short/pkg/res"
+)
+
+type WorkspaceHandlerDeps struct {
+	WorkspaceService *WorkspaceService
+	Auth             middleware.AuthDeps
+}
+
+type WorkspaceHandler struct {
+	WorkspaceService *WorkspaceService
+}
+
+func NewWorkspaceHandler(router *http.ServeMux, deps WorkspaceHandlerDeps) {
+	handler := &WorkspaceHandler{
+		WorkspaceService: deps.WorkspaceService,
+	}
+	router.Handle("POST /workspace", middleware.Authed(handler.Create(), user.RoleMember, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("GET /workspace", middleware.Authed(handler.GetAll(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("GET /workspace/{id}/members", middleware.Authed(handler.GetMembers(), user.RoleViewer, middleware.ScopeLinksRead, deps.Auth))
+	router.Handle("PUT /workspace/{id}/members", middleware.Authed(handler.SaveMember(), user.RoleViewer, middleware.ScopeLinksWrite, deps.Auth))
+	router.Handle("DELETE /workspace/{id}/members/{userId}", middleware.Authed(handler.RemoveMember(), user.RoleViewer, middleware.ScopeLinksWrite, deps.Auth))
+}
+
+func (handler *WorkspaceHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[CreateWorkspaceRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		workspace, err := handler.WorkspaceService.Create(email, body.Name)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, workspace, http.StatusCreated)
+	}
+}
+
+func (handler *WorkspaceHandler) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		workspaces, err := handler.WorkspaceService.List(email)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, workspaces, http.StatusOK)
+	}
+}
+
+func (handler *WorkspaceHandler) GetMembers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		members, err := handler.WorkspaceService.Members(email, uint(id))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, members, http.StatusOK)
+	}
+}
+
+func (handler *WorkspaceHandler) SaveMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := req.HandleBody[SaveMemberRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		membership, err := handler.WorkspaceService.SaveMember(email, uint(id), body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		res.Json(w, membership, http.StatusOK)
+	}
+}
+
+func (handler *WorkspaceHandler) RemoveMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		userID, err := strconv.ParseInt(r.PathValue("userId"), 10, 32)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		email, _ := r.Context().Value(middleware.ContextEmailKey).(string)
+		if err := handler.WorkspaceService.RemoveMember(email, uint(id), uint(userID)); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case ErrUserNotFound, ErrMemberNotFound:
+		return http.StatusNotFound
+	case ErrWorkspaceForbidden:
+		return http.StatusForbidden
+	case ErrLastAdmin:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
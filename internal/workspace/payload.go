@@ -0,0 +1,11 @@
+package workspace
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// SaveMemberRequest adds the user with given email to the workspace or changes their role.
+type SaveMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin member viewer"`
+}
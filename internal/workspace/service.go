@@ -0,0 +1,127 @@
+package workspace
+
+import (
+	"errors"
+	"url/short/internal/user"
+	"url/short/pkg/di"
+)
+
+type IWorkspaceRepository interface {
+	di.IMembershipRepository
+	CreateWorkspace(workspace *user.Workspace, ownerID uint) (*user.Workspace, error)
+	GetWorkspaces(userID uint) []user.Workspace
+	GetMembers(workspaceID uint) []user.Membership
+	SaveMembership(membership *user.Membership) (*user.Membership, error)
+	DeleteMembership(workspaceID, userID uint) error
+}
+
+type WorkspaceService struct {
+	UserRepository IWorkspaceRepository
+}
+
+func NewWorkspaceService(userRepository IWorkspaceRepository) *WorkspaceService {
+	return &WorkspaceService{
+		UserRepository: userRepository,
+	}
+}
+
+// Create creates a workspace with the user as its admin.
+func (service *WorkspaceService) Create(email, name string) (*user.Workspace, error) {
+	userID, err := service.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	return service.UserRepository.CreateWorkspace(&user.Workspace{Name: name}, userID)
+}
+
+// List returns the workspaces the user is a member of.
+func (service *WorkspaceService) List(email string) ([]user.Workspace, error) {
+	userID, err := service.userID(email)
+	if err != nil {
+		return nil, err
+	}
+	return service.UserRepository.GetWorkspaces(userID), nil
+}
+
+// Members lists members of the workspace to any of its members.
+func (service *WorkspaceService) Members(email string, workspaceID uint) ([]user.Membership, error) {
+	if err := service.authorize(email, workspaceID, user.RoleViewer); err != nil {
+		return nil, err
+	}
+	return service.UserRepository.GetMembers(workspaceID), nil
+}
+
+// SaveMember adds a user to the workspace or changes their role, only workspace admins may do it.
+func (service *WorkspaceService) SaveMember(email string, workspaceID uint, body *SaveMemberRequest) (*user.Membership, error) {
+	if err := service.authorize(email, workspaceID, user.RoleAdmin); err != nil {
+		return nil, err
+	}
+	member, _ := service.UserRepository.FindByEmail(body.Email)
+	if member == nil {
+		return nil, errors.New(ErrUserNotFound)
+	}
+	if body.Role != user.RoleAdmin {
+		if err := service.keepAdmin(workspaceID, member.ID); err != nil {
+			return nil, err
+		}
+	}
+	return service.UserRepository.SaveMembership(&user.Membership{
+		WorkspaceID: workspaceID,
+		UserID:      member.ID,
+		Role:        body.Role,
+	})
+}
+
+// RemoveMember removes a user from the workspace. Admins remove anyone, members may leave.
+func (service *WorkspaceService) RemoveMember(email string, workspaceID, userID uint) error {
+	currentID, err := service.userID(email)
+	if err != nil {
+		return err
+	}
+	if currentID != userID {
+		if err := service.authorize(email, workspaceID, user.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	if _, err := service.UserRepository.GetMembership(workspaceID, userID); err != nil {
+		return errors.New(ErrMemberNotFound)
+	}
+	if err := service.keepAdmin(workspaceID, userID); err != nil {
+		return err
+	}
+	return service.UserRepository.DeleteMembership(workspaceID, userID)
+}
+
+// keepAdmin fails when userID is the only admin of the workspace.
+func (service *WorkspaceService) keepAdmin(workspaceID, userID uint) error {
+	for _, membership := range service.UserRepository.GetMembers(workspaceID) {
+		if membership.Role == user.RoleAdmin && membership.UserID != userID {
+			return nil
+		}
+	}
+	membership, err := service.UserRepository.GetMembership(workspaceID, userID)
+	if err == nil && membership.Role == user.RoleAdmin {
+		return errors.New(ErrLastAdmin)
+	}
+	return nil
+}
+
+func (service *WorkspaceService) authorize(email string, workspaceID uint, role string) error {
+	userID, err := service.userID(email)
+	if err != nil {
+		return err
+	}
+	membership, err := service.UserRepository.GetMembership(workspaceID, userID)
+	if err != nil || !user.HasRole(membership.Role, role) {
+		return errors.New(ErrWorkspaceForbidden)
+	}
+	return nil
+}
+
+func (service *WorkspaceService) userID(email string) (uint, error) {
+	existedUser, _ := service.UserRepository.FindByEmail(email)
+	if existedUser == nil {
+		return 0, errors.New(ErrUserNotFound)
+	}
+	return existedUser.ID, nil
+}
@@ -0,0 +1,122 @@
+package workspace
+
+import (
+	"testing"
+	"url/short/internal/user"
+
+	"gorm.io/gorm"
+)
+
+type MockUserRepository struct {
+	users   []user.User
+	members []user.Membership
+}
+
+func (m *MockUserRepository) Create(u *user.User) (*user.User, error) {
+	return u, nil
+}
+
+func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+	for i := range m.users {
+		if m.users[i].Email == email {
+			return &m.users[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockUserRepository) GetById(id uint) (*user.User, error) {
+	for i := range m.users {
+		if m.users[i].ID == id {
+			return &m.users[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockUserRepository) GetMembership(workspaceID, userID uint) (*user.Membership, error) {
+	for i := range m.members {
+		if m.members[i].WorkspaceID == workspaceID && m.members[i].UserID == userID {
+			return &m.members[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockUserRepository) CreateWorkspace(w *user.Workspace, ownerID uint) (*user.Workspace, error) {
+	w.ID = 1
+	m.members = append(m.members, user.Membership{WorkspaceID: w.ID, UserID: ownerID, Role: user.RoleAdmin})
+	return w, nil
+}
+
+func (m *MockUserRepository) GetWorkspaces(userID uint) []user.Workspace {
+	return nil
+}
+
+func (m *MockUserRepository) GetMembers(workspaceID uint) []user.Membership {
+	var members []user.Membership
+	for _, membership := range m.members {
+		if membership.WorkspaceID == workspaceID {
+			members = append(members, membership)
+		}
+	}
+	return members
+}
+
+func (m *MockUserRepository) SaveMembership(membership *user.Membership) (*user.Membership, error) {
+	if existed, err := m.GetMembership(membership.WorkspaceID, membership.UserID); err == nil {
+		existed.Role = membership.Role
+		return existed, nil
+	}
+	m.members = append(m.members, *membership)
+	return membership, nil
+}
+
+func (m *MockUserRepository) DeleteMembership(workspaceID, userID uint) error {
+	for i := range m.members {
+		if m.members[i].WorkspaceID == workspaceID && m.members[i].UserID == userID {
+			m.members = append(m.members[:i], m.members[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func bootstrap(t *testing.T) *WorkspaceService {
+	service := NewWorkspaceService(&MockUserRepository{users: []user.User{
+		{Model: gorm.Model{ID: 1}, Email: "owner@mail.ru"},
+		{Model: gorm.Model{ID: 2}, Email: "a@mail.ru"},
+	}})
+	if _, err := service.Create("owner@mail.ru", "team"); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestOnlyAdminsManageMembers(t *testing.T) {
+	service := bootstrap(t)
+
+	if _, err := service.SaveMember("owner@mail.ru", 1, &SaveMemberRequest{Email: "a@mail.ru", Role: user.RoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := service.SaveMember("a@mail.ru", 1, &SaveMemberRequest{Email: "a@mail.ru", Role: user.RoleAdmin})
+	if err == nil || err.Error() != ErrWorkspaceForbidden {
+		t.Fatalf("Expected error %q, got %v", ErrWorkspaceForbidden, err)
+	}
+	if err := service.RemoveMember("a@mail.ru", 1, 2); err != nil {
+		t.Fatalf("Expected member to leave, got %v", err)
+	}
+}
+
+func TestLastAdminCantLeave(t *testing.T) {
+	service := bootstrap(t)
+
+	err := service.RemoveMember("owner@mail.ru", 1, 1)
+	if err == nil || err.Error() != ErrLastAdmin {
+		t.Fatalf("Expected error %q, got %v", ErrLastAdmin, err)
+	}
+	_, err = service.SaveMember("owner@mail.ru", 1, &SaveMemberRequest{Email: "owner@mail.ru", Role: user.RoleViewer})
+	if err == nil || err.Error() != ErrLastAdmin {
+		t.Fatalf("Expected error %q, got %v", ErrLastAdmin, err)
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"os"
+	"strings"
+	"url/short/internal/apikey"
+	"url/short/internal/domain"
 	"url/short/internal/link"
+	"url/short/internal/session"
 	"url/short/internal/stat"
 	"url/short/internal/user"
 
@@ -22,6 +26,86 @@ func main() {
 		panic("failed to connect database")
 	}
 
-	db.AutoMigrate(&link.Link{}, &user.User{}, &stat.Stat{})
+	if db.Migrator().HasTable(&stat.Stat{}) {
+		mergeDuplicateStats(db)
+	}
+
+	// aliases are unique per domain since custom domains
+	if db.Migrator().HasIndex(&link.Link{}, "idx_links_hash") {
+		if err := db.Migrator().DropIndex(&link.Link{}, "idx_links_hash"); err != nil {
+			panic("failed to drop links hash index")
+		}
+	}
+	// aliases of deleted links are free to reuse
+	if db.Migrator().HasIndex(&link.Link{}, "idx_links_domain_hash") {
+		if err := db.Migrator().DropIndex(&link.Link{}, "idx_links_domain_hash"); err != nil {
+			panic("failed to drop links domain hash index")
+		}
+	}
+	// links created before custom domains belong to the service's own host
+	fillNulls(db, &link.Link{}, "domain_id", 0)
+	// links created before max_clicks have not been visited under a limit
+	fillNulls(db, &link.Link{}, "clicks_used", 0)
+
+	db.AutoMigrate(&user.User{}, &user.Workspace{}, &user.Membership{}, &domain.Domain{}, &link.Tag{}, &link.Folder{}, &link.Link{}, &link.Rule{}, &stat.Stat{}, &stat.Click{}, &session.Session{}, &apikey.APIKey{})
+
+	promoteAdmins(db, os.Getenv("ADMIN_EMAILS"))
+}
+
+// promoteAdmins gives the admin role to users with comma-separated emails,
+// there is no other way to get the first admin.
+func promoteAdmins(db *gorm.DB, emails string) {
+	var list []string
+	for _, email := range strings.Split(emails, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			list = append(list, email)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+	err := db.Model(&user.User{}).Where("email IN ?", list).Update("role", user.RoleAdmin).Error
+	if err != nil {
+		panic("failed to promote admins")
+	}
+}
+
+// fillNulls sets NULLs of a column earlier migrations added as nullable to value,
+// so that AutoMigrate can make it NOT NULL. Deleted rows are filled too.
+func fillNulls(db *gorm.DB, model any, column string, value any) {
+	if !db.Migrator().HasColumn(model, column) {
+		return
+	}
+	err := db.Unscoped().Model(model).Where(column+" IS NULL").UpdateColumn(column, value).Error
+	if err != nil {
+		panic("failed to fill " + column)
+	}
+}
+
+// mergeDuplicateStats sums up counters of rows sharing (link_id, date)
+// so that the unique index on them can be created.
+func mergeDuplicateStats(db *gorm.DB) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE stats SET clicks = merged.clicks
+			FROM (
+				SELECT MIN(id) AS id, SUM(clicks) AS clicks
+				FROM stats
+				GROUP BY link_id, date
+				HAVING COUNT(*) > 1
+			) AS merged
+			WHERE stats.id = merged.id`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`
+			DELETE FROM stats USING stats AS kept
+			WHERE stats.link_id = kept.link_id
+				AND stats.date = kept.date
+				AND stats.id > kept.id`).Error
+	})
+	if err != nil {
+		panic("failed to merge duplicate stats")
+	}
 
 }
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected a")
+	}
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("Expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Expected a = 1, got %d", v)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Fatalf("Expected c = 3, got %d", v)
+	}
+
+	c.Set("a", 10, time.Minute)
+	c.Delete("c")
+	if v, _ := c.Get("a"); v != 10 || c.Len() != 1 {
+		t.Fatalf("Expected only a = 10, got %d of %d", v, c.Len())
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU[string, *int](10)
+	c.now = func() time.Time { return now }
+	c.Set("hit", new(int), time.Minute)
+	// nil values are kept like any other, callers use them for misses
+	c.Set("miss", nil, 10*time.Second)
+
+	now = now.Add(30 * time.Second)
+	if v, ok := c.Get("hit"); !ok || v == nil {
+		t.Fatal("Expected hit to live a minute")
+	}
+	if _, ok := c.Get("miss"); ok {
+		t.Fatal("Expected miss to expire")
+	}
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("hit"); ok || c.Len() != 0 {
+		t.Fatal("Expected hit to expire")
+	}
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	c := NewLRU[int, string](100)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := (i*1000 + j) % 150
+				c.Set(key, strconv.Itoa(key), time.Minute)
+				if v, ok := c.Get(key); ok && v != strconv.Itoa(key) {
+					t.Errorf("Expected %d, got %s", key, v)
+				}
+				c.Delete(key - 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if c.Len() > 100 {
+		t.Fatalf("Expected at most 100 values, got %d", c.Len())
+	}
+}
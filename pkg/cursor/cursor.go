@@ -0,0 +1,112 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+const ErrInvalid = "invalid cursor"
+
+// DefaultLimit is the page size when the limit parameter is not passed.
+const DefaultLimit = 10
+
+// Cursor points at a row of a listing ordered by (Key, ID). Clients get it
+// encoded and pass it back as is, Before asks for the page preceding the row.
+type Cursor struct {
+	// Sort names the order the cursor was issued for, a cursor of another order is rejected.
+	Sort   string `json:"s,omitempty"`
+	Key    string `json:"k,omitempty"`
+	ID     uint   `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func Decode(value string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New(ErrInvalid)
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, errors.New(ErrInvalid)
+	}
+	return &c, nil
+}
+
+// Page is a requested page of a listing, either by Cursor or by the older limit/offset.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+	// WithCount asks for the total number of rows, which costs a separate query.
+	WithCount bool
+}
+
+// ParsePage reads limit, offset, cursor and count query parameters.
+func ParsePage(r *http.Request) (*Page, error) {
+	params := r.URL.Query()
+	page := &Page{Limit: DefaultLimit}
+
+	if limit := params.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return nil, errors.New("Error with parsing limit")
+		}
+		page.Limit = l
+	}
+	if offset := params.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			return nil, errors.New("Error with parsing offset")
+		}
+		page.Offset = o
+	}
+	if value := params.Get("cursor"); value != "" {
+		if page.Offset != 0 {
+			return nil, errors.New("cursor and offset can't be used together")
+		}
+		c, err := Decode(value)
+		if err != nil {
+			return nil, err
+		}
+		page.Cursor = c
+	}
+	page.WithCount = params.Get("count") == "true"
+	return page, nil
+}
+
+// Paginate turns rows fetched with limit+1 into a page in the listing order
+// and returns cursors of the next and previous pages, empty when there are none.
+// Rows fetched for a Before cursor come in reverse order. at returns the cursor of a row.
+func Paginate[T any](rows []T, page *Page, at func(row T) *Cursor) ([]T, string, string) {
+	more := len(rows) > page.Limit
+	if more {
+		rows = rows[:page.Limit]
+	}
+	backward := page.Cursor != nil && page.Cursor.Before
+	if backward {
+		slices.Reverse(rows)
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	var next, prev string
+	if more || backward {
+		next = at(rows[len(rows)-1]).Encode()
+	}
+	if (more && backward) || (!backward && (page.Cursor != nil || page.Offset > 0)) {
+		c := at(rows[0])
+		c.Before = true
+		prev = c.Encode()
+	}
+	return rows, next, prev
+}
@@ -0,0 +1,85 @@
+package cursor
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	c := &Cursor{Sort: "created_at desc", Key: "2024-01-01 10:00:00+00", ID: 42, Before: true}
+
+	decoded, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *c {
+		t.Fatalf("Expected %+v, got %+v", c, decoded)
+	}
+	for _, value := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := Decode(value); err == nil || err.Error() != ErrInvalid {
+			t.Fatalf("%s: expected error %q, got %v", value, ErrInvalid, err)
+		}
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	page, err := ParsePage(httptest.NewRequest("GET", "/link?count=true", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Limit != DefaultLimit || page.Offset != 0 || page.Cursor != nil || !page.WithCount {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+
+	value := (&Cursor{ID: 5}).Encode()
+	if _, err := ParsePage(httptest.NewRequest("GET", "/link?offset=10&cursor="+value, nil)); err == nil {
+		t.Fatal("Expected error for cursor with offset")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	at := func(id uint) *Cursor { return &Cursor{ID: id} }
+	cases := []struct {
+		name       string
+		rows       []uint
+		page       *Page
+		want       []uint
+		next, prev uint
+	}{
+		{"first page", []uint{1, 2, 3}, &Page{Limit: 2}, []uint{1, 2}, 2, 0},
+		{"last page", []uint{3, 4}, &Page{Limit: 2, Cursor: &Cursor{ID: 2}}, []uint{3, 4}, 0, 3},
+		{"offset page", []uint{3}, &Page{Limit: 2, Offset: 2}, []uint{3}, 0, 3},
+		{"before", []uint{4, 3, 2}, &Page{Limit: 2, Cursor: &Cursor{ID: 5, Before: true}}, []uint{3, 4}, 4, 3},
+		{"before first", []uint{2, 1}, &Page{Limit: 2, Cursor: &Cursor{ID: 3, Before: true}}, []uint{1, 2}, 2, 0},
+		{"empty", nil, &Page{Limit: 2, Cursor: &Cursor{ID: 9}}, []uint{}, 0, 0},
+	}
+
+	for _, c := range cases {
+		rows, next, prev := Paginate(c.rows, c.page, at)
+		if len(rows) != len(c.want) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.want, rows)
+		}
+		for i := range rows {
+			if rows[i] != c.want[i] {
+				t.Fatalf("%s: expected %v, got %v", c.name, c.want, rows)
+			}
+		}
+		if got := cursorID(t, next); got != c.next {
+			t.Fatalf("%s: expected next cursor at %d, got %d", c.name, c.next, got)
+		}
+		if got := cursorID(t, prev); got != c.prev {
+			t.Fatalf("%s: expected prev cursor at %d, got %d", c.name, c.prev, got)
+		}
+	}
+}
+
+func cursorID(t *testing.T, value string) uint {
+	if value == "" {
+		return 0
+	}
+	c, err := Decode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.ID
+}
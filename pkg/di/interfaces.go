@@ -1,6 +1,10 @@
 package di
 
-import "url/short/internal/user"
+import (
+	"time"
+	"url/short/internal/session"
+	"url/short/internal/user"
+)
 
 type IStatRepository interface {
 	AddClick(linkId uint)
@@ -9,4 +13,37 @@ type IStatRepository interface {
 type IUserRepository interface {
 	Create(user *user.User) (*user.User, error)
 	FindByEmail(email string) (*user.User, error)
+	GetById(id uint) (*user.User, error)
+}
+
+// IMembershipRepository also knows workspace memberships of users.
+type IMembershipRepository interface {
+	IUserRepository
+	GetMembership(workspaceID, userID uint) (*user.Membership, error)
+}
+
+// IDomainRepository finds custom domains links are served on. D is *domain.Domain,
+// the domain package can't be imported here since it depends on this one.
+type IDomainRepository[D any] interface {
+	GetByHost(host string) (D, error)
+	GetById(id uint) (D, error)
+}
+
+type ISessionChecker interface {
+	IsActive(id uint) bool
+}
+
+type ISessionRepository interface {
+	ISessionChecker
+	Create(session *session.Session) (*session.Session, error)
+	FindByRefreshHash(hash string) (*session.Session, error)
+	FindByPreviousHash(hash string) (*session.Session, error)
+	Rotate(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error)
+	Revoke(id uint) error
+	RevokeByUser(userID uint) error
+}
+
+type IAPIKeyAuthenticator interface {
+	// Authenticate returns email and role of the key owner and scopes granted to the key.
+	Authenticate(key string) (string, string, []string, error)
 }
@@ -1,28 +1,175 @@
 package event
 
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"url/short/pkg/utm"
+)
+
 const (
 	EventLinkVisited = "link.visited"
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
 )
 
-type Event struct {
-	Type string
-	Data any
+// Event is a typed payload published on the bus under its topic.
+type Event interface {
+	Topic() string
+}
+
+type LinkVisited struct {
+	LinkID    uint
+	Time      time.Time
+	IP        string
+	UserAgent string
+	Referrer  string
+	// Source tells how the visitor came, "qr" for scanned codes, empty for direct clicks.
+	Source string
+	// UTM are the campaign parameters of the url the visitor was sent to.
+	UTM utm.Params
+	// RuleID is the redirect rule that sent the visitor, 0 when it went to the link's url.
+	RuleID uint
+}
+
+func (LinkVisited) Topic() string { return EventLinkVisited }
+
+type LinkCreated struct {
+	LinkID uint
+	UserID uint
+	Hash   string
+	Url    string
+}
+
+func (LinkCreated) Topic() string { return EventLinkCreated }
+
+type LinkUpdated struct {
+	LinkID  uint
+	Hash    string
+	OldHash string
+}
+
+func (LinkUpdated) Topic() string { return EventLinkUpdated }
+
+type LinkDeleted struct {
+	LinkID uint
+	Hash   string
+}
+
+func (LinkDeleted) Topic() string { return EventLinkDeleted }
+
+type subscriber struct {
+	deliver func(Event)
+	close   func()
 }
 
+// EventBus fans every published event out to all subscribers of its topic.
+// Each subscriber has its own buffer; a subscriber that falls behind
+// loses events instead of blocking publishers and other subscribers.
 type EventBus struct {
-	bus chan Event
+	mu     sync.RWMutex
+	topics map[string]map[*subscriber]struct{}
 }
 
 func NewEventBus() *EventBus {
 	return &EventBus{
-		bus: make(chan Event),
+		topics: make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+// Publish delivers event to the subscribers of its topic without blocking.
+func (e *EventBus) Publish(event Event) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for sub := range e.topics[event.Topic()] {
+		sub.deliver(event)
 	}
 }
 
-func (e *EventBus) Subscribe() <-chan Event {
-	return e.bus
+func (e *EventBus) add(topic string, sub *subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.topics[topic] == nil {
+		e.topics[topic] = make(map[*subscriber]struct{})
+	}
+	e.topics[topic][sub] = struct{}{}
 }
 
-func (e *EventBus) Publish(event Event) {
-	e.bus <- event
+func (e *EventBus) remove(topic string, sub *subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.topics[topic][sub]; !ok {
+		return
+	}
+	delete(e.topics[topic], sub)
+	// no Publish holds the read lock here, so closing is safe
+	sub.close()
+}
+
+// Subscription receives events of type T.
+type Subscription[T Event] struct {
+	bus       *EventBus
+	topic     string
+	sub       *subscriber
+	events    chan T
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Subscribe registers a subscriber for the topic of T with a buffer of the given size.
+func Subscribe[T Event](bus *EventBus, buffer int) *Subscription[T] {
+	var zero T
+	s := &Subscription[T]{
+		bus:    bus,
+		topic:  zero.Topic(),
+		events: make(chan T, buffer),
+	}
+	s.sub = &subscriber{
+		deliver: s.deliver,
+		close:   func() { close(s.events) },
+	}
+	bus.add(s.topic, s.sub)
+	return s
+}
+
+// Events is closed after Unsubscribe once buffered events are consumed.
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+func (s *Subscription[T]) Unsubscribe() {
+	s.bus.remove(s.topic, s.sub)
+}
+
+func (s *Subscription[T]) Delivered() uint64 {
+	return s.delivered.Load()
+}
+
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription[T]) Len() int {
+	return len(s.events)
+}
+
+func (s *Subscription[T]) Cap() int {
+	return cap(s.events)
+}
+
+func (s *Subscription[T]) deliver(event Event) {
+	payload, ok := event.(T)
+	if !ok {
+		return
+	}
+	select {
+	case s.events <- payload:
+		s.delivered.Add(1)
+	default:
+		s.dropped.Add(1)
+	}
 }
@@ -0,0 +1,52 @@
+package event
+
+import "testing"
+
+func TestPublishFansOutToEverySubscriber(t *testing.T) {
+	bus := NewEventBus()
+	first := Subscribe[LinkVisited](bus, 1)
+	second := Subscribe[LinkVisited](bus, 1)
+	deleted := Subscribe[LinkDeleted](bus, 1)
+
+	bus.Publish(LinkVisited{LinkID: 7})
+
+	for _, sub := range []*Subscription[LinkVisited]{first, second} {
+		visit := <-sub.Events()
+		if visit.LinkID != 7 {
+			t.Fatalf("Expected link 7, got %d", visit.LinkID)
+		}
+	}
+	if deleted.Len() != 0 {
+		t.Fatal("Expected other topics not to receive the event")
+	}
+}
+
+func TestSlowSubscriberDropsEvents(t *testing.T) {
+	bus := NewEventBus()
+	sub := Subscribe[LinkVisited](bus, 1)
+
+	bus.Publish(LinkVisited{LinkID: 1})
+	bus.Publish(LinkVisited{LinkID: 2})
+
+	if sub.Delivered() != 1 || sub.Dropped() != 1 {
+		t.Fatalf("Expected 1 delivered and 1 dropped, got %d and %d", sub.Delivered(), sub.Dropped())
+	}
+}
+
+func TestUnsubscribeClosesEvents(t *testing.T) {
+	bus := NewEventBus()
+	sub := Subscribe[LinkVisited](bus, 2)
+
+	bus.Publish(LinkVisited{LinkID: 1})
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	bus.Publish(LinkVisited{LinkID: 2})
+
+	var received []uint
+	for visit := range sub.Events() {
+		received = append(received, visit.LinkID)
+	}
+	if len(received) != 1 || received[0] != 1 {
+		t.Fatalf("Expected only events published before Unsubscribe, got %v", received)
+	}
+}
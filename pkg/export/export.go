@@ -0,0 +1,136 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// flushEvery rows the response is flushed so that clients receive data while it is read.
+const flushEvery = 500
+
+// Record is a row of an export, JSON Lines encode the record itself.
+type Record interface {
+	CSV() []string
+}
+
+// Negotiate picks the format from the format query parameter or the Accept header, CSV by default.
+func Negotiate(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case FormatCSV, FormatJSONL:
+		return format, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case "text/csv":
+			return FormatCSV, nil
+		case "application/x-ndjson", "application/jsonl", "application/json-lines":
+			return FormatJSONL, nil
+		}
+	}
+	return FormatCSV, nil
+}
+
+// Writer streams records to the response in the negotiated format.
+type Writer struct {
+	w       http.ResponseWriter
+	format  string
+	name    string
+	csv     *csv.Writer
+	json    *json.Encoder
+	written int
+}
+
+// NewWriter writes headers of an attachment named name with extension of the format.
+// CSV starts with the header row.
+func NewWriter(w http.ResponseWriter, format, name string, header []string) (*Writer, error) {
+	writer := &Writer{w: w, format: format, name: name}
+	switch format {
+	case FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer.csv = csv.NewWriter(w)
+	case FormatJSONL:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		writer.json = json.NewEncoder(w)
+	default:
+		return nil, errors.New("unknown format")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if writer.csv != nil {
+		if err := writer.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func (writer *Writer) Write(record Record) error {
+	var err error
+	if writer.csv != nil {
+		err = writer.csv.Write(escape(record.CSV()))
+	} else {
+		err = writer.json.Encode(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	writer.written++
+	if writer.written%flushEvery == 0 {
+		return writer.Flush()
+	}
+	return nil
+}
+
+// Flush sends buffered rows to the client.
+func (writer *Writer) Flush() error {
+	if writer.csv != nil {
+		writer.csv.Flush()
+		if err := writer.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := writer.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Finish flushes the rest of an export that ended with err. The status is already sent,
+// so an error only gets logged and the client sees a truncated file.
+func (writer *Writer) Finish(err error) {
+	if err != nil {
+		log.Println("Error exporting "+writer.name, err)
+	}
+	writer.Flush()
+}
+
+// escape prefixes cells spreadsheets would run as formulas with a quote.
+func escape(cells []string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type row struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (r row) CSV() []string {
+	return []string{r.Name, "1"}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		query  string
+		accept string
+		want   string
+	}{
+		{"", "", FormatCSV},
+		{"", "application/x-ndjson", FormatJSONL},
+		{"", "text/html, text/csv;q=0.9", FormatCSV},
+		{"format=jsonl", "text/csv", FormatJSONL},
+		{"format=NDJSON", "", FormatJSONL},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/export?"+c.query, nil)
+		r.Header.Set("Accept", c.accept)
+		if got, err := Negotiate(r); err != nil || got != c.want {
+			t.Fatalf("%q %q: expected %s, got %s %v", c.query, c.accept, c.want, got, err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/export?format=xml", nil)
+	if _, err := Negotiate(r); err == nil {
+		t.Fatal("Expected error for unknown format")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	cases := map[string]string{
+		FormatCSV:   "name,count\ngo,1\n",
+		FormatJSONL: "{\"name\":\"go\",\"count\":1}\n",
+	}
+	for format, want := range cases {
+		w := httptest.NewRecorder()
+		writer, err := NewWriter(w, format, "links", []string{"name", "count"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(row{Name: "go", Count: 1})
+		writer.Flush()
+
+		if got := w.Body.String(); got != want {
+			t.Fatalf("%s: expected %q, got %q", format, want, got)
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "links."+format) {
+			t.Fatalf("%s: unexpected Content-Disposition %q", format, w.Header().Get("Content-Disposition"))
+		}
+	}
+}
+
+func TestWriterEscapesFormulas(t *testing.T) {
+	w := httptest.NewRecorder()
+	writer, err := NewWriter(w, FormatCSV, "clicks", []string{"name", "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"=HYPERLINK(\"http://evil.dev\")", "+1", "-1", "@SUM(A1)", "\tx", "\rx", "a=b", ""} {
+		writer.Write(row{Name: name})
+	}
+	writer.Finish(nil)
+
+	want := "name,count\n\"'=HYPERLINK(\"\"http://evil.dev\"\")\",1\n'+1,1\n'-1,1\n'@SUM(A1),1\n'\tx,1\n\"'\rx\",1\na=b,1\n,1\n"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,34 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDB = `ip_start,ip_end,country
+1.0.0.0,1.0.0.255,AU
+5.8.0.0,5.8.255.255,ru
+2a00:1450::,2a00:1450:ffff:ffff:ffff:ffff:ffff:ffff,IE
+`
+
+func TestCountry(t *testing.T) {
+	db, err := Load(strings.NewReader(testDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"1.0.0.1":          "AU",
+		"5.8.10.20":        "RU",
+		"::ffff:5.8.10.20": "RU",
+		"2a00:1450::1":     "IE",
+		"1.0.1.0":          "",
+		"0.0.0.1":          "",
+		"not an ip":        "",
+	}
+	for ip, want := range cases {
+		if got := db.Country(ip); got != want {
+			t.Fatalf("Country(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
@@ -1,11 +1,22 @@
 package jwt
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTData struct {
-	Email string
+	Email     string
+	UserID    uint
+	Role      string
+	SessionID uint
+	// ID is the jti claim, generated when empty.
+	ID string
+	// ExpiresAt is the exp claim, tokens without it never expire.
+	ExpiresAt time.Time
 }
 
 type JWT struct {
@@ -19,9 +30,21 @@ func NewJWT(secret string) *JWT {
 }
 
 func (j *JWT) Create(data JWTData) (string, error) {
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	if data.ID == "" {
+		data.ID = uuid.NewString()
+	}
+	claims := jwt.MapClaims{
 		"email": data.Email,
-	})
+		"role":  data.Role,
+		"sub":   strconv.FormatUint(uint64(data.UserID), 10),
+		"sid":   data.SessionID,
+		"jti":   data.ID,
+		"iat":   time.Now().Unix(),
+	}
+	if !data.ExpiresAt.IsZero() {
+		claims["exp"] = data.ExpiresAt.Unix()
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	s, err := t.SignedString([]byte(j.Secret))
 	if err != nil {
@@ -34,15 +57,30 @@ func (j *JWT) Create(data JWTData) (string, error) {
 func (j *JWT) Parse(token string) (bool, *JWTData) {
 	t, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
 		return []byte(j.Secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return false, nil
 	}
 
-	email := t.Claims.(jwt.MapClaims)["email"]
+	claims := t.Claims.(jwt.MapClaims)
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+	userID, _ := strconv.ParseUint(sub, 10, 64)
+	sid, _ := claims["sid"].(float64)
 
-	return t.Valid, &JWTData{
-		Email: email.(string),
+	data := &JWTData{
+		Email:     email,
+		UserID:    uint(userID),
+		Role:      role,
+		SessionID: uint(sid),
+		ID:        jti,
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		data.ExpiresAt = exp.Time
 	}
+
+	return t.Valid, data
 }
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"testing"
+	"time"
 )
 
 func TestJwtCreate(t *testing.T) {
@@ -26,3 +27,43 @@ func TestJwtCreate(t *testing.T) {
 	}
 
 }
+
+func TestJwtExpired(t *testing.T) {
+	jwtService := NewJWT("/2+XnmJGz1j3ehIVI/5P9kl+CghrE3DcS7rnT+qar5w=")
+
+	token, err := jwtService.Create(JWTData{
+		Email:     "email4@mail.ru",
+		UserID:    4,
+		SessionID: 9,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isValid, _ := jwtService.Parse(token); isValid {
+		t.Fatalf("expired token is valid")
+	}
+}
+
+func TestJwtClaims(t *testing.T) {
+	jwtService := NewJWT("/2+XnmJGz1j3ehIVI/5P9kl+CghrE3DcS7rnT+qar5w=")
+
+	token, err := jwtService.Create(JWTData{
+		Email:     "email4@mail.ru",
+		UserID:    4,
+		SessionID: 9,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isValid, data := jwtService.Parse(token)
+	if !isValid {
+		t.Fatalf("token is not valid")
+	}
+	if data.UserID != 4 || data.SessionID != 9 || data.ID == "" {
+		t.Fatalf("unexpected claims %+v", data)
+	}
+}
@@ -0,0 +1,251 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	ErrPrivateAddress = "address is not public"
+	ErrNotHTML        = "page is not html"
+)
+
+const maxRedirects = 5
+
+// Metadata describes a page for display next to its link. URLs are absolute.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+	Favicon     string
+}
+
+// Fetcher downloads a page and extracts its metadata.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
+
+type HTTPFetcherOptions struct {
+	// Timeout bounds the whole request including redirects and reading the body.
+	Timeout  time.Duration
+	MaxBytes int64
+	// AllowPrivate lets the fetcher connect to loopback and private networks,
+	// it is meant for tests only.
+	AllowPrivate bool
+}
+
+// HTTPFetcher fetches pages over HTTP. It connects only to public addresses:
+// the check runs on the resolved address of every connection, redirects included,
+// so neither a redirect nor a DNS answer can point it into the internal network.
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewHTTPFetcher(opts HTTPFetcherOptions) *HTTPFetcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !IsPublic(addrPort.Addr()) {
+				return errors.New(ErrPrivateAddress)
+			}
+			return nil
+		}
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			Transport: &http.Transport{
+				// no proxy from the environment, it would connect on our behalf
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   opts.Timeout,
+				ResponseHeaderTimeout: opts.Timeout,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("too many redirects")
+				}
+				return checkScheme(req.URL)
+			},
+		},
+		maxBytes: opts.MaxBytes,
+	}
+}
+
+// IsPublic reports whether addr is a unicast address of the public internet.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedPrefixes are not covered by the netip predicates.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "shortly-preview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, errors.New(ErrNotHTML)
+	}
+
+	return Parse(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL), nil
+}
+
+// Parse reads metadata from the head of an HTML document, relative URLs are resolved against base.
+// OpenGraph title is preferred over <title>, the favicon falls back to /favicon.ico.
+func Parse(r io.Reader, base *url.URL) *Metadata {
+	var meta Metadata
+	var title, ogTitle string
+
+	tokenizer := html.NewTokenizer(r)
+loop:
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			break loop
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if atom.Lookup(name) == atom.Head {
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Body {
+				break loop
+			}
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+
+			switch tag {
+			case atom.Title:
+				if tokenType == html.StartTagToken && title == "" && tokenizer.Next() == html.TextToken {
+					title = string(tokenizer.Text())
+				}
+			case atom.Meta:
+				content := attrs["content"]
+				switch strings.ToLower(attrs["property"] + attrs["name"]) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					meta.Description = content
+				case "description":
+					if meta.Description == "" {
+						meta.Description = content
+					}
+				case "og:image", "og:image:url":
+					if meta.Image == "" {
+						meta.Image = resolve(base, content)
+					}
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if rel == "icon" && meta.Favicon == "" {
+						meta.Favicon = resolve(base, attrs["href"])
+					}
+				}
+			}
+		}
+	}
+
+	meta.Title = clean(title)
+	if ogTitle = clean(ogTitle); ogTitle != "" {
+		meta.Title = ogTitle
+	}
+	meta.Description = clean(meta.Description)
+	if meta.Favicon == "" {
+		meta.Favicon = resolve(base, "/favicon.ico")
+	}
+	return &meta
+}
+
+// clean collapses whitespace and cuts text to a length fit for display.
+func clean(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 300 {
+		text = string(runes[:300])
+	}
+	return text
+}
+
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || checkScheme(u) != nil {
+		return ""
+	}
+	return u.String()
+}
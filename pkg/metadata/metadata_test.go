@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<title>
+  Summer   sale
+</title>
+<meta name="description" content="Plain description">
+<meta property="og:description" content="Everything -50%">
+<meta property="og:image" content="/img/cover.png">
+<link rel="shortcut icon" href="https://cdn.example.com/icon.png">
+</head>
+<body><title>Not a title</title></body></html>`
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/sale", http.StatusMovedPermanently)
+		case "/sale":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(page))
+		case "/file":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF"))
+		}
+	}))
+	defer server.Close()
+	fetcher := NewHTTPFetcher(HTTPFetcherOptions{Timeout: time.Second, MaxBytes: 1 << 20, AllowPrivate: true})
+
+	meta, err := fetcher.Fetch(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{
+		Title:       "Summer sale",
+		Description: "Everything -50%",
+		Image:       server.URL + "/img/cover.png",
+		Favicon:     "https://cdn.example.com/icon.png",
+	}
+	if *meta != want {
+		t.Fatalf("Expected %+v, got %+v", want, *meta)
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/file"); err == nil || err.Error() != ErrNotHTML {
+		t.Fatalf("Expected error %q, got %v", ErrNotHTML, err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Fatal("Expected error for file scheme")
+	}
+}
+
+func TestFetchRejectsPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request reached a loopback server")
+	}))
+	defer server.Close()
+	fetcher := NewHTTPFetcher(HTTPFetcherOptions{Timeout: time.Second, MaxBytes: 1 << 20})
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), ErrPrivateAddress) {
+		t.Fatalf("Expected error %q, got %v", ErrPrivateAddress, err)
+	}
+}
+
+func TestFetchReadsUpToMaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + "<title>Too far</title>"))
+	}))
+	defer server.Close()
+	fetcher := NewHTTPFetcher(HTTPFetcherOptions{Timeout: time.Second, MaxBytes: 1024, AllowPrivate: true})
+
+	meta, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "" {
+		t.Fatalf("Expected no title past the limit, got %q", meta.Title)
+	}
+	if meta.Favicon != server.URL+"/favicon.ico" {
+		t.Fatalf("Expected default favicon, got %q", meta.Favicon)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+	for ip, want := range cases {
+		if got := IsPublic(netip.MustParseAddr(ip)); got != want {
+			t.Fatalf("%s: expected %v, got %v", ip, want, got)
+		}
+	}
+}
@@ -3,40 +3,130 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 	"url/short/configs"
+	"url/short/internal/user"
+	"url/short/pkg/di"
 	"url/short/pkg/jwt"
 )
 
 type key string
 
 const (
-	ContextEmailKey key = "ContextEmailKey"
+	ContextEmailKey   key = "ContextEmailKey"
+	ContextSessionKey key = "ContextSessionKey"
+	ContextRoleKey    key = "ContextRoleKey"
+	// ContextScopesKey holds scopes of an API key, requests authed with a JWT have none and may do anything.
+	ContextScopesKey key = "ContextScopesKey"
 )
 
+// APIKeyPrefix tells API keys from JWTs in the Authorization header.
+const APIKeyPrefix = "sk_"
+
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+	// ScopeKeysWrite and ScopeAdmin are never granted to API keys,
+	// so keys can't mint other keys or act as an administrator.
+	ScopeKeysWrite = "keys:write"
+	ScopeAdmin     = "admin"
+)
+
+// APIKeyScopes can be granted to an API key.
+var APIKeyScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+type AuthDeps struct {
+	Config   *configs.Config
+	Sessions di.ISessionChecker
+	APIKeys  di.IAPIKeyAuthenticator
+}
+
 func writeUnauthorized(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
 	w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
 }
 
-func IsAuthed(next http.Handler, config *configs.Config) http.Handler {
+func writeForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(http.StatusText(http.StatusForbidden)))
+}
+
+// IsAuthed accepts a Bearer JWT of an active session or an API key
+// sent in X-API-Key or as a Bearer token starting with APIKeyPrefix.
+func IsAuthed(next http.Handler, deps AuthDeps) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authedHeader := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authedHeader, "Bearer ") {
+		token := strings.TrimPrefix(authedHeader, "Bearer ")
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			token = apiKey
+		} else if !strings.HasPrefix(authedHeader, "Bearer ") {
 			writeUnauthorized(w)
 			return
 		}
-		token := strings.TrimPrefix(authedHeader, "Bearer ")
-		isValid, data := jwt.NewJWT(config.Auth.Secret).Parse(token)
+
+		if strings.HasPrefix(token, APIKeyPrefix) {
+			email, role, scopes, err := deps.APIKeys.Authenticate(token)
+			if err != nil {
+				writeUnauthorized(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextEmailKey, email)
+			ctx = context.WithValue(ctx, ContextRoleKey, role)
+			ctx = context.WithValue(ctx, ContextScopesKey, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		isValid, data := jwt.NewJWT(deps.Config.Auth.Secret).Parse(token)
 
 		if !isValid {
 			writeUnauthorized(w)
 			return
 		}
 
+		// tokens of a logged out or expired session are revoked
+		if data.SessionID == 0 || !deps.Sessions.IsActive(data.SessionID) {
+			writeUnauthorized(w)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), ContextEmailKey, data.Email)
+		ctx = context.WithValue(ctx, ContextSessionKey, data.SessionID)
+		ctx = context.WithValue(ctx, ContextRoleKey, data.Role)
 
 		req := r.WithContext(ctx)
 		next.ServeHTTP(w, req)
 	})
 }
+
+// Authed lets through users with at least role, API keys also need scope.
+func Authed(next http.Handler, role, scope string, deps AuthDeps) http.Handler {
+	return IsAuthed(RequireScope(RequireRole(next, role), scope), deps)
+}
+
+// RequireRole rejects users whose role is lower than role. It must be wrapped by IsAuthed.
+func RequireRole(next http.Handler, role string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userRole, _ := r.Context().Value(ContextRoleKey).(string)
+		if !user.HasRole(userRole, role) {
+			writeForbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope rejects API keys without the scope. It must be wrapped by IsAuthed.
+func RequireScope(next http.Handler, scope string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes, isAPIKey := r.Context().Value(ContextScopesKey).([]string)
+		if isAPIKey && !slices.Contains(scopes, scope) {
+			writeForbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url/short/configs"
+	"url/short/internal/user"
+	"url/short/pkg/jwt"
+)
+
+type MockSessions struct{}
+
+func (m *MockSessions) IsActive(id uint) bool {
+	return id == 1
+}
+
+type MockAPIKeys struct{}
+
+func (m *MockAPIKeys) Authenticate(key string) (string, string, []string, error) {
+	if key != "sk_valid" {
+		return "", "", nil, errors.New("api key not found")
+	}
+	return "a@mail.ru", user.RoleMember, []string{ScopeLinksRead}, nil
+}
+
+func authDeps() AuthDeps {
+	return AuthDeps{
+		Config:   &configs.Config{Auth: configs.Authconfig{Secret: "secret"}},
+		Sessions: &MockSessions{},
+		APIKeys:  &MockAPIKeys{},
+	}
+}
+
+func serve(handler http.Handler, header, value string) int {
+	r := httptest.NewRequest(http.MethodGet, "/link", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestIsAuthed(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	active, _ := jwt.NewJWT("secret").Create(jwt.JWTData{Email: "a@mail.ru", SessionID: 1})
+	revoked, _ := jwt.NewJWT("secret").Create(jwt.JWTData{Email: "a@mail.ru", SessionID: 2})
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		scope  string
+		want   int
+	}{
+		{"no credentials", "", "", ScopeLinksRead, http.StatusUnauthorized},
+		{"jwt", "Authorization", "Bearer " + active, ScopeKeysWrite, http.StatusOK},
+		{"revoked session", "Authorization", "Bearer " + revoked, ScopeLinksRead, http.StatusUnauthorized},
+		{"api key header", "X-API-Key", "sk_valid", ScopeLinksRead, http.StatusOK},
+		{"api key bearer", "Authorization", "Bearer sk_valid", ScopeLinksRead, http.StatusOK},
+		{"unknown api key", "X-API-Key", "sk_unknown", ScopeLinksRead, http.StatusUnauthorized},
+		{"api key without scope", "X-API-Key", "sk_valid", ScopeLinksWrite, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		handler := IsAuthed(RequireScope(ok, c.scope), authDeps())
+		if got := serve(handler, c.header, c.value); got != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.name, c.want, got)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	viewer, _ := jwt.NewJWT("secret").Create(jwt.JWTData{Email: "v@mail.ru", Role: user.RoleViewer, SessionID: 1})
+	admin, _ := jwt.NewJWT("secret").Create(jwt.JWTData{Email: "a@mail.ru", Role: user.RoleAdmin, SessionID: 1})
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		role   string
+		want   int
+	}{
+		{"viewer reads", "Authorization", "Bearer " + viewer, user.RoleViewer, http.StatusOK},
+		{"viewer writes", "Authorization", "Bearer " + viewer, user.RoleMember, http.StatusForbidden},
+		{"admin writes", "Authorization", "Bearer " + admin, user.RoleMember, http.StatusOK},
+		{"member api key", "X-API-Key", "sk_valid", user.RoleMember, http.StatusOK},
+		{"member api key as admin", "X-API-Key", "sk_valid", user.RoleAdmin, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		handler := IsAuthed(RequireRole(ok, c.role), authDeps())
+		if got := serve(handler, c.header, c.value); got != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestReedSolomon checks the example of ISO/IEC 18004 annex I: "01234567" as version 1-M.
+func TestReedSolomon(t *testing.T) {
+	data := []byte{16, 32, 12, 86, 97, 128, 236, 17, 236, 17, 236, 17, 236, 17, 236, 17}
+	want := []byte{165, 36, 212, 193, 237, 54, 199, 135, 44, 85}
+
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	cases := []struct {
+		version int
+		total   int
+		data    [4]int
+	}{
+		{1, 26, [4]int{19, 16, 13, 9}},
+		{2, 44, [4]int{34, 28, 22, 16}},
+		{5, 134, [4]int{108, 86, 62, 46}},
+		{7, 196, [4]int{156, 124, 88, 66}},
+		{10, 346, [4]int{274, 216, 154, 122}},
+		{40, 3706, [4]int{2956, 2334, 1666, 1276}},
+	}
+	for _, c := range cases {
+		if got := rawModules(c.version) / 8; got != c.total {
+			t.Fatalf("version %d: expected %d codewords, got %d", c.version, c.total, got)
+		}
+		for level, want := range c.data {
+			if got := dataCodewords(c.version, Level(level)); got != want {
+				t.Fatalf("version %d level %d: expected %d data codewords, got %d", c.version, level, want, got)
+			}
+		}
+	}
+	// every version and level must split its codewords into whole blocks
+	for level := Low; level <= High; level++ {
+		for version := 1; version <= 40; version++ {
+			raw := rawModules(version) / 8
+			if raw/blocks[level][version] <= eccPerBlock[level][version] {
+				t.Fatalf("version %d level %d: blocks leave no data", version, level)
+			}
+		}
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	c := &Code{Size: 21, Version: 1, Level: Medium, modules: newGrid(21), isFunction: newGrid(21)}
+	c.drawFormatBits(0)
+	// format information of level M with mask 0 from ISO/IEC 18004 annex C
+	want := "101010000010010"
+	var got strings.Builder
+	for i := 14; i >= 9; i-- {
+		got.WriteString(bit(c.modules[8][14-i]))
+	}
+	got.WriteString(bit(c.modules[8][7]))
+	got.WriteString(bit(c.modules[8][8]))
+	got.WriteString(bit(c.modules[7][8]))
+	for i := 5; i >= 0; i-- {
+		got.WriteString(bit(c.modules[i][8]))
+	}
+	if got.String() != want {
+		t.Fatalf("Expected format bits %s, got %s", want, got.String())
+	}
+
+	c = &Code{Size: 45, Version: 7, modules: newGrid(45), isFunction: newGrid(45)}
+	c.drawVersion()
+	// version information of version 7 from annex D: 000111 110010 010100
+	want = "000111110010010100"
+	got.Reset()
+	for i := 17; i >= 0; i-- {
+		got.WriteString(bit(c.modules[i/3][c.Size-11+i%3]))
+	}
+	if got.String() != want {
+		t.Fatalf("Expected version bits %s, got %s", want, got.String())
+	}
+}
+
+func bit(dark bool) string {
+	if dark {
+		return "1"
+	}
+	return "0"
+}
+
+func TestEncode(t *testing.T) {
+	c, err := Encode([]byte("https://sho.rt/promo"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 2 || c.Size != 25 {
+		t.Fatalf("Expected version 2 of 25 modules, got %d of %d", c.Version, c.Size)
+	}
+	// finder patterns in three corners with the light separator
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		x, y := corner[0], corner[1]
+		if !c.Dark(x, y) || !c.Dark(x+6, y+6) || c.Dark(x+1, y+1) || !c.Dark(x+3, y+3) {
+			t.Fatalf("No finder pattern at %v", corner)
+		}
+	}
+	// alignment pattern of version 2 is centered at (18, 18)
+	if !c.Dark(18, 18) || c.Dark(17, 18) || !c.Dark(16, 18) {
+		t.Fatal("No alignment pattern at (18, 18)")
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Fatal("No dark module")
+	}
+
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2954), High); err == nil || err.Error() != ErrTooLong {
+		t.Fatalf("Expected error %q, got %v", ErrTooLong, err)
+	}
+	if c, err := Encode(bytes.Repeat([]byte("a"), 2953), Low); err != nil || c.Version != 40 {
+		t.Fatalf("Expected version 40, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderOptions describe the image of a code. Size is the side of the image in pixels,
+// PNG modules are whole pixels so the image may come out a bit smaller.
+// Margin is the quiet zone in modules, scanners expect at least 4.
+type RenderOptions struct {
+	Size       int
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+var (
+	Black = color.RGBA{A: 0xFF}
+	White = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+)
+
+// ParseColor reads a hex color as RGB or RRGGBB with an optional leading #.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return color.RGBA{}, errors.New("color must be a hex RGB value like ff0000")
+	}
+	return color.RGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 0xFF}, nil
+}
+
+func (c *Code) PNG(w io.Writer, opts RenderOptions) error {
+	total := c.Size + opts.Margin*2
+	scale := max(opts.Size/total, 1)
+
+	img := image.NewPaletted(image.Rect(0, 0, total*scale, total*scale), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			top, left := (y+opts.Margin)*scale, (x+opts.Margin)*scale
+			for py := top; py < top+scale; py++ {
+				for px := left; px < left+scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// SVG draws dark modules as one path in a viewBox of modules, so it scales without blur.
+func (c *Code) SVG(w io.Writer, opts RenderOptions) error {
+	total := c.Size + opts.Margin*2
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="%s"/><path fill="%s" d="%s"/></svg>`,
+		opts.Size, opts.Size, total, total, hex(opts.Background), hex(opts.Foreground), path.String())
+	return err
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
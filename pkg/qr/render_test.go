@@ -0,0 +1,74 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	cases := map[string]color.RGBA{
+		"ff0000":  {R: 0xFF, A: 0xFF},
+		"#0a0B0c": {R: 0x0A, G: 0x0B, B: 0x0C, A: 0xFF},
+		"fff":     White,
+	}
+	for s, want := range cases {
+		got, err := ParseColor(s)
+		if err != nil || got != want {
+			t.Fatalf("%s: expected %v, got %v (%v)", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"", "red", "ff00", "gg0000", "#ff00000"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Fatalf("%s: expected error", s)
+		}
+	}
+}
+
+func TestPNG(t *testing.T) {
+	c, err := Encode([]byte("https://sho.rt/promo"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	var buf bytes.Buffer
+	if err := c.PNG(&buf, RenderOptions{Size: 200, Margin: 4, Foreground: red, Background: White}); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 25 modules and 8 of margin fit 6 pixels each into 200
+	if img.Bounds().Dx() != 198 || img.Bounds().Dy() != 198 {
+		t.Fatalf("Expected 198x198 image, got %v", img.Bounds())
+	}
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != White {
+		t.Fatalf("Expected white margin, got %v", got)
+	}
+	// the top left module of the finder pattern is dark
+	if got := color.RGBAModel.Convert(img.At(4*6, 4*6)); got != red {
+		t.Fatalf("Expected red module, got %v", got)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode([]byte("https://sho.rt/promo"), Low)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.SVG(&buf, RenderOptions{Size: 300, Margin: 2, Foreground: Black, Background: White}); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := buf.String()
+	for _, want := range []string{`width="300"`, `viewBox="0 0 29 29"`, `fill="#ffffff"`, `fill="#000000"`, "M2 2h1v1h-1z"} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("Expected %s in %s", want, svg[:200])
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package req
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// DateRange reads from/to (YYYY-MM-DD, both inclusive) of a query string.
+// to is returned as the start of the following day, missing dates are nil.
+func DateRange(params url.Values) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if value := params.Get("from"); value != "" {
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, nil, errors.New("Error with parse from param")
+		}
+		from = &t
+	}
+	if value := params.Get("to"); value != "" {
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, nil, errors.New("Error with parse to param")
+		}
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+	return from, to, nil
+}
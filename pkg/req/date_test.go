@@ -0,0 +1,26 @@
+package req
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDateRange(t *testing.T) {
+	params, _ := url.ParseQuery("from=2024-03-01&to=2024-03-31")
+	from, to, err := DateRange(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected range %v - %v", from, to)
+	}
+
+	if from, to, err := DateRange(url.Values{}); from != nil || to != nil || err != nil {
+		t.Fatalf("Expected no range, got %v - %v %v", from, to, err)
+	}
+	params, _ = url.ParseQuery("to=31.03.2024")
+	if _, _, err := DateRange(params); err == nil || err.Error() != "Error with parse to param" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package useragent
+
+import "strings"
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+
+	Other = "Other"
+)
+
+type Info struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+type rule struct {
+	token string
+	name  string
+}
+
+// Order matters: Chromium based browsers also mention Chrome and Safari.
+var browsers = []rule{
+	{"Edg", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera", "Opera"},
+	{"SamsungBrowser", "Samsung Internet"},
+	{"YaBrowser", "Yandex"},
+	{"FxiOS", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"MSIE", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+}
+
+// iOS goes before macOS because iPad user agents contain "like Mac OS X".
+var systems = []rule{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iOS"},
+	{"iPod", "iOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+var bots = []string{"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests", "go-http-client", "facebookexternalhit"}
+
+// Parse extracts browser, operating system and device class from a User-Agent header.
+func Parse(ua string) Info {
+	if ua == "" {
+		return Info{Browser: Other, OS: Other, Device: DeviceDesktop}
+	}
+
+	info := Info{
+		Browser: match(ua, browsers),
+		OS:      match(ua, systems),
+		Device:  DeviceDesktop,
+	}
+
+	lower := strings.ToLower(ua)
+	switch {
+	case containsAny(lower, bots):
+		info.Device = DeviceBot
+	case strings.Contains(ua, "iPad") || strings.Contains(lower, "tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		info.Device = DeviceTablet
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPod"):
+		info.Device = DeviceMobile
+	}
+
+	return info
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if strings.Contains(ua, r.token) {
+			return r.name
+		}
+	}
+	return Other
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := map[string]Info{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36":                         {"Chrome", "Windows", DeviceDesktop},
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0":               {"Edge", "Windows", DeviceDesktop},
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1": {"Safari", "iOS", DeviceMobile},
+		"Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1":          {"Safari", "iOS", DeviceTablet},
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36":                   {"Chrome", "Android", DeviceMobile},
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:127.0) Gecko/20100101 Firefox/127.0":                                                     {"Firefox", "macOS", DeviceDesktop},
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                                                                {Other, Other, DeviceBot},
+		"curl/8.5.0": {Other, Other, DeviceBot},
+	}
+
+	for ua, want := range cases {
+		if got := Parse(ua); got != want {
+			t.Fatalf("Parse(%q) = %+v, want %+v", ua, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package utm
+
+import "net/url"
+
+// Params are the utm_* campaign parameters analytics of target pages read.
+type Params struct {
+	Source   string `json:"source" validate:"max=200"`
+	Medium   string `json:"medium" validate:"max=200"`
+	Campaign string `json:"campaign" validate:"max=200"`
+	Term     string `json:"term" validate:"max=200"`
+	Content  string `json:"content" validate:"max=200"`
+}
+
+func (p Params) IsZero() bool {
+	return p == Params{}
+}
+
+// FromQuery reads utm_* parameters of a query.
+func FromQuery(values url.Values) Params {
+	return Params{
+		Source:   values.Get("utm_source"),
+		Medium:   values.Get("utm_medium"),
+		Campaign: values.Get("utm_campaign"),
+		Term:     values.Get("utm_term"),
+		Content:  values.Get("utm_content"),
+	}
+}
+
+// Set writes the non-empty parameters into the query replacing existing values.
+func (p Params) Set(values url.Values) {
+	for name, value := range map[string]string{
+		"utm_source":   p.Source,
+		"utm_medium":   p.Medium,
+		"utm_campaign": p.Campaign,
+		"utm_term":     p.Term,
+		"utm_content":  p.Content,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+}
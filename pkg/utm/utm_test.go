@@ -0,0 +1,22 @@
+package utm
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	values, _ := url.ParseQuery("utm_source=x&utm_medium=email&utm_medium=sms&utm_term=go&page=2")
+	params := FromQuery(values)
+	if params != (Params{Source: "x", Medium: "email", Term: "go"}) {
+		t.Fatalf("Unexpected params %+v", params)
+	}
+
+	Params{Medium: "print", Content: "a"}.Set(values)
+	if values.Encode() != "page=2&utm_content=a&utm_medium=print&utm_source=x&utm_term=go" {
+		t.Fatalf("Unexpected query %s", values.Encode())
+	}
+	if params.IsZero() || !(Params{}).IsZero() {
+		t.Fatal("Unexpected IsZero")
+	}
+}